@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
+	"unicode"
 
 	"github.com/miguel250/lisp-interpreter/scope"
 	"github.com/miguel250/lisp-interpreter/syntax"
@@ -11,204 +13,363 @@ import (
 
 // builtins holds all go fuction to make it available at run time.
 type builtins struct {
-	fn map[syntax.SymbolExpr]syntax.Sexpr
+	fn map[*syntax.SymbolExpr]syntax.Sexpr
 }
 
 // add new function to builtins internal map.
 func (b *builtins) add(name string, fn scope.Function) {
-	s := syntax.SymbolExpr{Token: syntax.SYMBOL, Name: name}
 	f := scope.FuncExpr{Name: name, Fn: fn}
-	b.fn[s] = &f
+	b.fn[syntax.Intern(name)] = &f
+}
+
+// addControl adds a function which needs the continuation of its
+// call.
+func (b *builtins) addControl(name string, f *scope.FuncExpr) {
+	b.fn[syntax.Intern(name)] = f
+}
+
+// addCxr adds all compositions of car and cdr from two to four levels
+// deep such as cadr and cdddr.
+func (b *builtins) addCxr() {
+	names := []string{"a", "d"}
+
+	for depth := 2; depth <= 4; depth++ {
+		next := make([]string, 0, len(names)*2)
+
+		for _, n := range names {
+			next = append(next, n+"a", n+"d")
+		}
+
+		names = next
+
+		for _, n := range names {
+			b.add("c"+n+"r", cxr("c"+n+"r", n))
+		}
+	}
 }
 
 // newBuiltins returns an instance of builtins with all built-in functions
 // added.
 func newBuiltins() *builtins {
-	b := &builtins{fn: make(map[syntax.SymbolExpr]syntax.Sexpr)}
+	b := &builtins{fn: make(map[*syntax.SymbolExpr]syntax.Sexpr)}
+	b.fn[printCircleSymbol] = &syntax.NilExpr{}
 
-	b.add("setq", builtinSetq)
 	b.add("print", builtinPrint)
 	b.add("list", builtinList)
-	b.add("first", builtinFirst)
+	b.add("list*", builtinListStar)
+	b.add("cons", builtinCons)
+	b.add("car", builtinCar)
+	b.add("first", builtinCar)
+	b.add("cdr", builtinCdr)
+	b.add("rest", builtinCdr)
+	b.add("nth", builtinNth)
+	b.add("nthcdr", builtinNthcdr)
+	b.add("last", builtinLast)
+	b.add("length", builtinLength)
+	b.add("append", builtinAppend)
+	b.add("reverse", builtinReverse)
+	b.add("member", builtinMember)
+	b.add("assoc", builtinAssoc)
+	b.add("copy-list", builtinCopyList)
+	b.add("setcar", setter("setcar", true))
+	b.add("set-car!", setter("set-car!", true))
+	b.add("setcdr", setter("setcdr", false))
+	b.add("set-cdr!", setter("set-cdr!", false))
+	b.addCxr()
+	b.add("mapcar", builtinMapcar)
+	b.add("mapc", builtinMapc)
+	b.add("filter", filter("filter", true))
+	b.add("remove-if-not", filter("remove-if-not", true))
+	b.add("remove-if", filter("remove-if", false))
+	b.add("reduce", builtinReduce)
+	b.add("some", some("some", true))
+	b.add("every", some("every", false))
+	b.add("find-if", builtinFindIf)
+	b.add("position", builtinPosition)
+	b.addControl("apply", applyList)
+	b.addControl("funcall", funcall)
+	b.addControl("call/cc", callCC)
+	b.addControl("call-with-current-continuation", callCC)
+	b.addControl("dynamic-wind", dynamicWind)
+	b.add("make-vector", builtinMakeVector)
+	b.add("vector", builtinVector)
+	b.add("aref", builtinAref)
+	b.add("aset", builtinAset)
+	b.add("vector-length", builtinVectorLength)
+	b.add("vector-push", builtinVectorPush)
+	b.add("subseq", builtinSubseq)
+	b.add("vector-to-list", builtinVectorToList)
+	b.add("list-to-vector", builtinListToVector)
+	b.add("concat", builtinConcat)
+	b.add("substring", builtinSubstring)
+	b.add("string-length", builtinStringLength)
+	b.add("string-upcase", stringFunction("string-upcase", strings.ToUpper))
+	b.add("string-downcase", stringFunction("string-downcase", strings.ToLower))
+	b.add("string-split", builtinStringSplit)
+	b.add("string-join", builtinStringJoin)
+	b.add("string-trim", builtinStringTrim)
+	b.add("string-index", builtinStringIndex)
+	b.add("string-replace", builtinStringReplace)
+	b.add("string-prefix-p", stringPredicate("string-prefix-p", func(a, b string) bool { return strings.HasPrefix(b, a) }))
+	b.add("string-suffix-p", stringPredicate("string-suffix-p", func(a, b string) bool { return strings.HasSuffix(b, a) }))
+	b.add("string=", stringPredicate("string=", func(a, b string) bool { return a == b }))
+	b.add("string<", stringPredicate("string<", func(a, b string) bool { return a < b }))
+	b.add("string>", stringPredicate("string>", func(a, b string) bool { return a > b }))
+	b.add("number-to-string", builtinNumberToString)
+	b.add("string-to-number", builtinStringToNumber)
+	b.add("char", builtinChar)
+	b.add("char-code", builtinCharCode)
+	b.add("code-char", builtinCodeChar)
+	b.add("char-upcase", charFunction("char-upcase", unicode.ToUpper))
+	b.add("char-downcase", charFunction("char-downcase", unicode.ToLower))
+	b.add("char-alphabetic-p", charPredicate("char-alphabetic-p", unicode.IsLetter))
+	b.add("char-digit-p", charPredicate("char-digit-p", unicode.IsDigit))
+	b.add("string-to-list", builtinStringToList)
+	b.add("list-to-string", builtinListToString)
+	b.add("intern", builtinIntern)
+	b.add("symbol-name", builtinSymbolName)
+	b.add("make-symbol", builtinMakeSymbol)
+	b.add("gensym", builtinGensym)
+	b.add("symbol-value", builtinSymbolValue)
+	b.add("get", builtinGet)
+	b.add("put", builtinPut)
+	b.add("remprop", builtinRemprop)
+	b.add("symbol-plist", builtinSymbolPlist)
+	b.add("make-hash-table", builtinMakeHashTable)
+	b.add("gethash", builtinGethash)
+	b.add("puthash", builtinPuthash)
+	b.add("remhash", builtinRemhash)
+	b.add("clrhash", builtinClrhash)
+	b.add("hash-table-count", builtinHashTableCount)
+	b.add("hash-table-keys", builtinHashTableKeys)
+	b.add("maphash", builtinMaphash)
 	b.add("+", builtinAdd)
+	b.add("-", builtinSub)
+	b.add("*", builtinMul)
+	b.add("/", builtinDiv)
+	b.add("mod", numberFunction(modOp))
+	b.add("rem", numberFunction(remOp))
+	b.add("quotient", numberFunction(quotientOp))
+	b.add("abs", builtinAbs)
+	b.add("min", extremum("min", -1))
+	b.add("max", extremum("max", 1))
+	b.add("=", comparison("=", func(c int) bool { return c == 0 }))
+	b.add("<", comparison("<", func(c int) bool { return c < 0 }))
+	b.add(">", comparison(">", func(c int) bool { return c > 0 }))
+	b.add("<=", comparison("<=", func(c int) bool { return c <= 0 }))
+	b.add(">=", comparison(">=", func(c int) bool { return c >= 0 }))
+	b.add("/=", builtinNotEqual)
+	b.add("zerop", builtinZerop)
+	b.add("evenp", parity("evenp", 0))
+	b.add("oddp", parity("oddp", 1))
+	b.add("1+", increment("1+", 1))
+	b.add("1-", increment("1-", -1))
+	b.add("expt", builtinExpt)
+	b.add("error", builtinError)
+	b.add("signal", builtinSignal)
+	b.add("throw", builtinThrow)
+	b.add("invoke-restart", builtinInvokeRestart)
+	b.add("eq", equality("eq", syntax.Eq))
+	b.add("eql", equality("eql", syntax.Eql))
+	b.add("equal", equality("equal", syntax.Equal))
+	b.add("equalp", equality("equalp", syntax.Equalp))
+	b.add("null", predicate("null", isNull))
+	b.add("not", predicate("not", isNull))
+	b.add("atom", predicate("atom", isAtom))
+	b.add("consp", predicate("consp", isCons))
+	b.add("listp", predicate("listp", isList))
+	b.add("symbolp", predicate("symbolp", isSymbolExpr))
+	b.add("numberp", predicate("numberp", isNumber))
+	b.add("integerp", predicate("integerp", isInteger))
+	b.add("floatp", predicate("floatp", isFloat))
+	b.add("stringp", predicate("stringp", isString))
+	b.add("functionp", predicate("functionp", isFunction))
+	b.add("hash-table-p", predicate("hash-table-p", isHashTable))
+	b.add("vectorp", predicate("vectorp", isVector))
+	b.add("characterp", predicate("characterp", isChar))
+	b.add("keywordp", predicate("keywordp", isKeyword))
+	b.add("type-of", builtinTypeOf)
+	b.add("macroexpand", builtinMacroexpand)
+	b.add("macroexpand-1", builtinMacroexpand1)
 	return b
 }
 
-// builtinSetq adds a s-expression into scope. It will failed
-// if not enough arguments are pass to it.
-func builtinSetq(s *scope.Scope, ss []syntax.Sexpr) (syntax.Sexpr, error) {
-	if len(ss) < 2 {
-		return nil, fmt.Errorf("setq needs two arguments")
-	}
-
-	symbol := ss[0].(*syntax.SymbolExpr)
-	expr, err := eval(ss[1], s)
-	if err != nil {
-		return nil, err
-	}
+// printCircleSymbol names the variable which makes the printer label
+// shared structure and not only cycles.
+var printCircleSymbol = syntax.Intern("*print-circle*")
 
-	s.Set(*symbol, expr)
-	return expr, nil
+// printCircle reports whether the current value of *print-circle* is
+// true.
+func printCircle(s *scope.Scope) bool {
+	e, err := symbolValue(s, printCircleSymbol)
+	return err == nil && isTrue(e)
 }
 
-// builtinPrint prints a s-expression into the stdout.
-func builtinPrint(s *scope.Scope, ss []syntax.Sexpr) (syntax.Sexpr, error) {
-	if len(ss) < 1 {
+// builtinPrint prints a s-expression into the stdout and returns it.
+func builtinPrint(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 1 {
 		return nil, fmt.Errorf("print needs an argument")
 	}
 
-	args, err := evalArgs(s, ss)
-
-	if err != nil {
-		return nil, err
-	}
-
 	var buf bytes.Buffer
 	expr := args[0]
 
 	switch e := expr.(type) {
 	case *syntax.ConsExpr:
-		formatCons(&buf, e, true)
+		formatCons(&buf, e, printCircle(s))
+	case *syntax.VectorExpr:
+		writeValue(&buf, e, syntax.NewLabels(e, printCircle(s)))
 	default:
 		fmt.Fprintf(&buf, "%s", expr)
 	}
 
 	fmt.Println(buf.String())
-	return nil, nil
+	return expr, nil
 }
 
 // formatCons formats consExpr in a more readable way only adding parentheses
-// when necessary.
-// ( 4 1 ( 4 1 2 ))
-func formatCons(src io.Writer, cons *syntax.ConsExpr, parenthese bool) {
-	if parenthese {
-		fmt.Fprint(src, "( ")
-	}
+// when necessary. A list which does not end with nil is written with a dot
+// before its last cdr. Conses and vectors which are part of a cycle, or
+// reached more than once when shared is set, are written with #n= labels.
+// ( 4 1 ( 4 1 2 ) #( 5 ) . 3 )
+// #1=( 1 2 . #1# )
+func formatCons(src io.Writer, cons *syntax.ConsExpr, shared bool) {
+	writeCons(src, cons, syntax.NewLabels(cons, shared), true)
+}
 
-	// car can be a consExpr or atomExpr
-	car, ok := cons.Car.(*syntax.ConsExpr)
+// writeValue writes a value of a list or a vector for formatCons.
+func writeValue(src io.Writer, e syntax.Sexpr, labels *syntax.Labels) {
+	switch e := e.(type) {
+	case *syntax.ConsExpr:
+		writeCons(src, e, labels, true)
+	case *syntax.VectorExpr:
+		if writeLabel(src, e, labels) {
+			return
+		}
 
-	if ok {
-		formatCons(src, car, true)
-	} else {
-		fmt.Fprintf(src, "%s ", cons.Car)
-	}
+		fmt.Fprint(src, "#( ")
 
-	cdr, ok := cons.Cdr.(*syntax.ConsExpr)
-	if ok {
-		formatCons(src, cdr, false)
-	}
+		for _, item := range e.Items {
+			writeValue(src, item, labels)
+		}
 
-	if parenthese {
 		fmt.Fprint(src, ")")
+	default:
+		fmt.Fprintf(src, "%s ", e)
 	}
-
 }
 
-// builtinList creates a list by linking a set of const together.
-// (cons 4 (cons 5 (cons 6 nil)))
-func builtinList(s *scope.Scope, ss []syntax.Sexpr) (syntax.Sexpr, error) {
-	if len(ss) < 1 {
-		return nil, fmt.Errorf("list needs an argument")
-	}
-
-	ss, err := evalArgs(s, ss)
+// writeLabel writes the label of a cons or a vector when it has one and
+// reports whether it was only written as a reference to its label.
+func writeLabel(src io.Writer, e syntax.Sexpr, labels *syntax.Labels) bool {
+	n, printed := labels.Label(e)
 
-	if err != nil {
-		return nil, err
+	if printed {
+		fmt.Fprintf(src, "#%d# ", n)
+		return true
 	}
 
-	var expr syntax.Sexpr
-	expr = &syntax.NilExpr{}
-
-	for i := len(ss) - 1; i >= 0; i-- {
-		expr = &syntax.ConsExpr{Car: ss[i], Cdr: expr}
+	if n > 0 {
+		fmt.Fprintf(src, "#%d=", n)
 	}
 
-	return expr, nil
+	return false
 }
 
-// builtinFirst returns the first value of a list (const.car).
-func builtinFirst(s *scope.Scope, ss []syntax.Sexpr) (syntax.Sexpr, error) {
-	if len(ss) < 1 {
-		return nil, fmt.Errorf("first needs an argument")
-	}
-
-	args, err := evalArgs(s, ss)
+// writeCons writes the values of a cons for formatCons.
+func writeCons(src io.Writer, cons *syntax.ConsExpr, labels *syntax.Labels, parenthese bool) {
+	if parenthese {
+		if writeLabel(src, cons, labels) {
+			return
+		}
 
-	if err != nil {
-		return nil, err
+		fmt.Fprint(src, "( ")
 	}
 
-	cons, ok := args[0].(*syntax.ConsExpr)
-
-	if !ok {
-		return nil, fmt.Errorf("Unable to convert expression to cons: {%v}", args[0])
-	}
-	return cons.Car, nil
-}
+	writeValue(src, cons.Car, labels)
 
-// builtinAdd adds two number of the same type together
-func builtinAdd(s *scope.Scope, ss []syntax.Sexpr) (syntax.Sexpr, error) {
-	if len(ss) < 2 && len(ss) > 2 {
-		return nil, fmt.Errorf("Addition only takes 2 args")
+	switch cdr := cons.Cdr.(type) {
+	case *syntax.ConsExpr:
+		// a labelled tail has to be written as the cdr of a dotted list.
+		if labels.Has(cdr) {
+			fmt.Fprint(src, ". ")
+			writeCons(src, cdr, labels, true)
+		} else {
+			writeCons(src, cdr, labels, false)
+		}
+	case *syntax.NilExpr:
+	default:
+		fmt.Fprint(src, ". ")
+		writeValue(src, cdr, labels)
 	}
 
-	args, err := evalArgs(s, ss)
-
-	if err != nil {
-		return nil, err
+	if parenthese {
+		fmt.Fprint(src, ")")
 	}
 
-	firstArg, ok := args[0].(*syntax.AtomExpr)
+}
 
-	if !ok {
-		return nil, fmt.Errorf("Failed to add %s", ss)
+// boolExpr returns t when b is true and nil otherwise.
+func boolExpr(b bool) syntax.Sexpr {
+	if b {
+		return syntax.True
 	}
+	return &syntax.NilExpr{}
+}
 
-	secondArg, ok := args[1].(*syntax.AtomExpr)
+// A cycleCheck finds out whether a list walked one cons at a time is
+// circular. A tortoise follows the walk at half its speed, they meet on
+// the same cons only when the list is circular.
+type cycleCheck struct {
+	tortoise *syntax.ConsExpr
+	odd      bool
+}
 
-	if !ok {
-		return nil, fmt.Errorf("Failed to add %s", ss)
+// visit reports whether the walk came back to a cons it went through.
+func (c *cycleCheck) visit(cons *syntax.ConsExpr) bool {
+	if c.tortoise == nil {
+		c.tortoise = cons
+		return false
 	}
 
-	if firstArg.Token != secondArg.Token {
-		return nil, fmt.Errorf("Arguments have to be the same type got: %s %s", firstArg.Token, secondArg.Token)
+	if c.odd {
+		c.tortoise = c.tortoise.Cdr.(*syntax.ConsExpr)
 	}
 
-	var total int64
-	var totalFloat float64
-
-	switch firstArg.Token {
-	case syntax.INT:
-		total = firstArg.Value.(int64) + secondArg.Value.(int64)
-	case syntax.FLOAT:
-		totalFloat = firstArg.Value.(float64) + secondArg.Value.(float64)
-	default:
-		return nil, fmt.Errorf("Unsupported type")
-	}
+	c.odd = !c.odd
+	return c.tortoise == cons
+}
 
-	totalAtom := &syntax.AtomExpr{}
-	if firstArg.Token == syntax.INT {
-		totalAtom.Value = total
-		totalAtom.Token = syntax.INT
-	} else {
-		totalAtom.Value = totalFloat
-		totalAtom.Token = syntax.FLOAT
+// listToSlice returns the elements of a list. It fails when the list
+// does not end with nil or is circular.
+func listToSlice(e syntax.Sexpr) ([]syntax.Sexpr, error) {
+	list := make([]syntax.Sexpr, 0)
+	var cycle cycleCheck
+
+	for {
+		switch cons := e.(type) {
+		case *syntax.NilExpr:
+			return list, nil
+		case *syntax.ConsExpr:
+			if cycle.visit(cons) {
+				return nil, fmt.Errorf("Circular list: %s", e)
+			}
+
+			list = append(list, cons.Car)
+			e = cons.Cdr
+		default:
+			return nil, fmt.Errorf("Not a proper list: %s", e)
+		}
 	}
-	return totalAtom, nil
 }
 
-// evalArgs evaluate all arguments pass to a function when necessary and
-// returns a slice with the s-expressions.
-func evalArgs(s *scope.Scope, ss []syntax.Sexpr) ([]syntax.Sexpr, error) {
-	args := make([]syntax.Sexpr, 0, 0)
-	for _, e := range ss {
-		e, err := eval(e, s)
-		if err != nil {
-			return nil, err
-		}
-		args = append(args, e)
+// sliceToList links a slice of s-expressions into a list.
+func sliceToList(ss []syntax.Sexpr) syntax.Sexpr {
+	var expr syntax.Sexpr = &syntax.NilExpr{}
+
+	for i := len(ss) - 1; i >= 0; i-- {
+		expr = &syntax.ConsExpr{Car: ss[i], Cdr: expr}
 	}
-	return args, nil
+
+	return expr
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+func TestFormatCons(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		shared bool
+		want   string
+	}{
+		{`'(1 2 3)`, false, "( 1 2 3 )"},
+		{`'(4 1 (4 1 2))`, false, "( 4 1 ( 4 1 2 ))"},
+		{`'(1 . 2)`, false, "( 1 . 2 )"},
+		{`'(1 2 . 3)`, false, "( 1 2 . 3 )"},
+		{`'((a . 1) (b . 2))`, false, "( ( a . 1 )( b . 2 ))"},
+		{"(setq x (list 1 2))\n(setcdr (cdr x) x)\nx", false, "#1=( 1 2 . #1# )"},
+		{"(setq x (list 1 2))\n(setcar x x)\nx", false, "#1=( #1# 2 )"},
+		{"(setq x (list 1))\n(list x x)", false, "( ( 1 )( 1 ))"},
+		{"(setq x (list 1))\n(list x x)", true, "( #1=( 1 )#1# )"},
+		{"(setq x (list 2))\n(cons 1 (cons x x))", true, "( 1 #1=( 2 ). #1# )"},
+		{`'#1=(a . #1#)`, true, "#1=( a . #1# )"},
+		{`'(1 [2 (3)] . [4])`, false, "( 1 #( 2 ( 3 )). #( 4 ))"},
+		{"(setq v (vector 1))\n(setq x (list v))\n(aset v 0 x)\nx", false, "#1=( #( #1# ))"},
+	} {
+		e, err := evalString(test.input, newTestScope())
+
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		var buf bytes.Buffer
+		formatCons(&buf, e.(*syntax.ConsExpr), test.shared)
+
+		if got := buf.String(); got != test.want {
+			t.Errorf("formatCons `%s` = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
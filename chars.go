@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// newChar wraps a rune into a character atom.
+func newChar(r rune) *syntax.AtomExpr {
+	return &syntax.AtomExpr{Token: syntax.CHAR, Value: r}
+}
+
+// charArg returns the value of a character atom.
+func charArg(name string, e syntax.Sexpr) (rune, error) {
+	atom, ok := e.(*syntax.AtomExpr)
+
+	if !ok || atom.Token != syntax.CHAR {
+		return 0, fmt.Errorf("%s needs a character got: %s", name, e)
+	}
+
+	return atom.Value.(rune), nil
+}
+
+// charFunction returns a built-in function which takes one character and
+// returns a character.
+// (char-upcase #\a) => #\A
+func charFunction(name string, fn func(rune) rune) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		r, err := charArg(name, args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return newChar(fn(r)), nil
+	}
+}
+
+// charPredicate returns a built-in function which is true when test is
+// true for a character.
+// (char-digit-p #\1) => t
+func charPredicate(name string, test func(rune) bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		r, err := charArg(name, args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return boolExpr(test(r)), nil
+	}
+}
+
+// builtinChar returns the character at an index of a string.
+// (char "héllo" 1) => #\é
+func builtinChar(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("char needs two arguments")
+	}
+
+	str, err := stringArg("char", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := indexArg("char", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(str)
+
+	if i >= len(runes) {
+		return nil, fmt.Errorf("char index out of range got: %d", i)
+	}
+
+	return newChar(runes[i]), nil
+}
+
+// builtinCharCode returns the unicode code point of a character.
+// (char-code #\A) => 65
+func builtinCharCode(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("char-code needs one argument")
+	}
+
+	r, err := charArg("char-code", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newNumber(int64(r)), nil
+}
+
+// builtinCodeChar returns the character of a unicode code point.
+// (code-char 65) => #\A
+func builtinCodeChar(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("code-char needs one argument")
+	}
+
+	code, err := indexArg("code-char", args[0])
+
+	if err != nil || code > unicode.MaxRune {
+		return nil, fmt.Errorf("code-char needs a character code got: %s", args[0])
+	}
+
+	return newChar(rune(code)), nil
+}
+
+// builtinStringToList returns a list with the characters of a string.
+// (string-to-list "ab") => (#\a #\b)
+func builtinStringToList(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("string-to-list needs one argument")
+	}
+
+	str, err := stringArg("string-to-list", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]syntax.Sexpr, 0, len(str))
+
+	for _, r := range str {
+		items = append(items, newChar(r))
+	}
+
+	return sliceToList(items), nil
+}
+
+// builtinListToString returns a string with the characters of a list.
+// (list-to-string '(#\a #\b)) => "ab"
+func builtinListToString(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("list-to-string needs one argument")
+	}
+
+	list, err := properList("list-to-string", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	runes := make([]rune, len(list))
+
+	for i, item := range list {
+		if runes[i], err = charArg("list-to-string", item); err != nil {
+			return nil, err
+		}
+	}
+
+	return newString(string(runes)), nil
+}
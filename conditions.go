@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+var (
+	// conditionSymbol is the type every condition is of.
+	conditionSymbol = syntax.Intern("condition")
+	// errorSymbol is the type of conditions raised by error.
+	errorSymbol = syntax.Intern("error")
+)
+
+// A condition is raised by the error and signal built-in functions. It
+// is returned as the error of eval so Go callers can read the Lisp value
+// it carries.
+type condition struct {
+	typ      *syntax.SymbolExpr
+	value    syntax.Sexpr
+	signaled bool // raised by signal instead of error
+}
+
+// newError returns a condition raised by error. A nil type is the error
+// type.
+func newError(typ *syntax.SymbolExpr, value syntax.Sexpr) *condition {
+	if typ == nil {
+		typ = errorSymbol
+	}
+	return &condition{typ: typ, value: value}
+}
+
+// ofType reports whether a condition is of a type. Every condition is a
+// condition and the ones raised by error are errors too.
+func (c *condition) ofType(typ *syntax.SymbolExpr) bool {
+	switch typ {
+	case c.typ, conditionSymbol:
+		return true
+	case errorSymbol:
+		return !c.signaled
+	}
+	return false
+}
+
+// Error returns the value of the condition, strings are written without
+// quotes. It starts with the type unless the type is error.
+func (c *condition) Error() string {
+	msg := c.value.String()
+
+	if atom, ok := c.value.(*syntax.AtomExpr); ok && atom.Token == syntax.STRING {
+		msg = atom.Value.(string)
+	}
+
+	if c.typ == errorSymbol {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", c.typ.Name, msg)
+}
+
+// A thrown is returned as an error by throw until a catch with the same
+// tag returns its value.
+type thrown struct {
+	tag, value syntax.Sexpr
+}
+
+func (t *thrown) Error() string {
+	return fmt.Sprintf("throw with no catch for tag: %s", t.tag)
+}
+
+// conditionOf returns the condition for an error. Errors of built-in
+// functions are conditions of type error whose value is their message.
+// Throws, aborts, restarts and escaping continuations are not conditions.
+func conditionOf(err error) (*condition, bool) {
+	switch err := err.(type) {
+	case *condition:
+		return err, true
+	case *thrown, *aborted, *restartInvocation, *escape:
+		return nil, false
+	}
+	return newError(nil, newString(err.Error())), true
+}
+
+// handles reports whether a handler for typ handles a condition. t
+// handles every condition.
+func handles(typ syntax.Sexpr, c *condition) bool {
+	if _, ok := typ.(*syntax.TrueExpr); ok {
+		return true
+	}
+
+	symbol, ok := typ.(*syntax.SymbolExpr)
+	return ok && c.ofType(symbol)
+}
+
+// handled reports whether a handler-case or ignore-errors being
+// evaluated handles a condition.
+func handled(s *scope.Scope, c *condition) bool {
+	for k := currentContinuation(s); k != nil; k = k.next {
+		switch f := k.frame.(type) {
+		case *handlerFrame:
+			if f.clause(c) != nil {
+				return true
+			}
+		case ignoreErrorsFrame:
+			if c.ofType(errorSymbol) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newCondition creates a condition from the arguments of error or
+// signal, a value or a condition type and an optional value.
+func newCondition(name string, args []syntax.Sexpr, signaled bool) (*condition, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("%s needs a value or a condition type and a value", name)
+	}
+
+	c := newError(nil, args[0])
+
+	if typ, ok := args[0].(*syntax.SymbolExpr); ok && !typ.IsKeyword() {
+		c.typ = typ
+		c.value = &syntax.NilExpr{}
+
+		if len(args) == 2 {
+			c.value = args[1]
+		}
+	} else if len(args) == 2 {
+		return nil, fmt.Errorf("%s needs a symbol as condition type got: %s", name, args[0])
+	}
+
+	c.signaled = signaled
+	return c, nil
+}
+
+// builtinError raises a condition of type error or of the given type.
+// (error "not found")
+// (error 'not-found key)
+func builtinError(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	c, err := newCondition("error", args, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, c
+}
+
+// builtinSignal raises a condition like error when a handler-case
+// handles it, otherwise it returns nil.
+// (signal 'progress 50)
+func builtinSignal(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	c, err := newCondition("signal", args, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if handled(s, c) {
+		return nil, c
+	}
+
+	return &syntax.NilExpr{}, nil
+}
+
+// builtinThrow returns from the nearest catch with the same tag.
+// (throw 'done 1)
+func builtinThrow(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("throw needs a tag and a value")
+	}
+
+	return nil, &thrown{tag: args[0], value: args[1]}
+}
+
+// A handlerClause is a clause of handler-case.
+type handlerClause struct {
+	typ   syntax.Sexpr
+	param *syntax.SymbolExpr // nil when the value is not bound
+	body  []syntax.Sexpr
+}
+
+// parseHandlerClause parses a clause of handler-case.
+// (error (e) (print e) nil)
+func parseHandlerClause(e syntax.Sexpr) (*handlerClause, error) {
+	list, err := listToSlice(e)
+
+	if err != nil || len(list) < 2 {
+		return nil, fmt.Errorf("handler-case clause needs a type and a parameter list got: %s", e)
+	}
+
+	switch list[0].(type) {
+	case *syntax.SymbolExpr, *syntax.TrueExpr:
+	default:
+		return nil, fmt.Errorf("handler-case needs a symbol as condition type got: %s", list[0])
+	}
+
+	params, err := listToSlice(list[1])
+
+	if err != nil || len(params) > 1 {
+		return nil, fmt.Errorf("handler-case clause needs at most one parameter got: %s", list[1])
+	}
+
+	clause := &handlerClause{typ: list[0], body: list[2:]}
+
+	if len(params) == 1 {
+		param, ok := params[0].(*syntax.SymbolExpr)
+
+		if !ok || param.IsKeyword() {
+			return nil, fmt.Errorf("handler-case parameter has to be a symbol got: %s", params[0])
+		}
+
+		clause.param = param
+	}
+
+	return clause, nil
+}
+
+// A handlerFrame is the frame of the expression of handler-case.
+type handlerFrame struct {
+	passFrame
+	s       *scope.Scope
+	clauses []*handlerClause
+}
+
+// clause returns the first clause handling a condition or nil.
+func (f *handlerFrame) clause(c *condition) *handlerClause {
+	for _, clause := range f.clauses {
+		if handles(clause.typ, c) {
+			return clause
+		}
+	}
+	return nil
+}
+
+func (f *handlerFrame) catch(err error, k *continuation) (state, bool, error) {
+	c, ok := conditionOf(err)
+
+	if !ok {
+		return state{}, false, err
+	}
+
+	clause := f.clause(c)
+
+	if clause == nil {
+		return state{}, false, err
+	}
+
+	local := scope.NewScope(f.s)
+	bound := k
+
+	if clause.param != nil {
+		bound = bindVariable(local, clause.param, c.value, k)
+	}
+
+	st, err := enterBindings(k, bound, func(k *continuation) (state, error) {
+		return evalSequence(clause.body, local, k)
+	})
+	return st, true, err
+}
+
+// formHandlerCase evaluates an expression and, when it raises a
+// condition, the body of the first clause handling it with the value of
+// the condition bound to the parameter.
+// (handler-case (error "boom") (error (e) e))
+func formHandlerCase(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("handler-case needs an expression")
+	}
+
+	clauses := make([]*handlerClause, 0, len(ss)-1)
+
+	for _, e := range ss[1:] {
+		clause, err := parseHandlerClause(e)
+
+		if err != nil {
+			return state{}, err
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return evaluate(ss[0], s, k.push(&handlerFrame{s: s, clauses: clauses}))
+}
+
+// An ignoreErrorsFrame is the frame of the body of ignore-errors.
+type ignoreErrorsFrame struct {
+	passFrame
+}
+
+func (ignoreErrorsFrame) catch(err error, k *continuation) (state, bool, error) {
+	if c, ok := conditionOf(err); ok && c.ofType(errorSymbol) {
+		st, err := pass(&syntax.NilExpr{}, k)
+		return st, true, err
+	}
+
+	return state{}, false, err
+}
+
+// formIgnoreErrors evaluates its body and returns nil when it raises an
+// error.
+// (ignore-errors (car 1))
+func formIgnoreErrors(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return evalSequence(ss, s, k.push(ignoreErrorsFrame{}))
+}
+
+// A protectFrame is the frame of the expression of unwind-protect. The
+// cleanup body is evaluated when the expression returns or is left by
+// an error, a throw or a continuation.
+type protectFrame struct {
+	s       *scope.Scope
+	cleanup []syntax.Sexpr
+}
+
+func (f *protectFrame) resume(v syntax.Sexpr, k *continuation) (state, error) {
+	return evalSequence(f.cleanup, f.s, k.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+		return pass(v, k)
+	}))
+}
+
+func (f *protectFrame) exit(k *continuation) (state, error) {
+	return evalSequence(f.cleanup, f.s, k)
+}
+
+// formUnwindProtect evaluates an expression and then its cleanup body
+// even when the expression raises a condition or throws. It returns the
+// value of the expression.
+// (unwind-protect (read-file f) (close f))
+func formUnwindProtect(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("unwind-protect needs an expression")
+	}
+
+	return evaluate(ss[0], s, k.push(&protectFrame{s: s, cleanup: ss[1:]}))
+}
+
+// A catchFrame is the frame of the body of catch.
+type catchFrame struct {
+	passFrame
+	tag syntax.Sexpr
+}
+
+func (f *catchFrame) catch(err error, k *continuation) (state, bool, error) {
+	if t, ok := err.(*thrown); ok && syntax.Eq(t.tag, f.tag) {
+		st, err := pass(t.value, k)
+		return st, true, err
+	}
+
+	return state{}, false, err
+}
+
+// formCatch evaluates its body and returns the value thrown to its tag
+// or the value of the body.
+// (catch 'done (throw 'done 1) 2)
+func formCatch(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("catch needs a tag")
+	}
+
+	return evaluate(ss[0], s, k.then(func(tag syntax.Sexpr, k *continuation) (state, error) {
+		return evalSequence(ss[1:], s, k.push(&catchFrame{tag: tag}))
+	}))
+}
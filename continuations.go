@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// A continuation is what is left to do with the value of an expression:
+// a frame and the continuation after it. Continuations are never
+// changed so call/cc can capture one and resume it any number of times.
+type continuation struct {
+	frame frame
+	next  *continuation
+	depth int
+}
+
+// push returns a continuation running f before k.
+func (k *continuation) push(f frame) *continuation {
+	return &continuation{frame: f, next: k, depth: k.size() + 1}
+}
+
+// then returns a continuation calling fn with a value before k.
+func (k *continuation) then(fn func(syntax.Sexpr, *continuation) (state, error)) *continuation {
+	return k.push(thenFrame(fn))
+}
+
+// result passes v to k or returns err when it is not nil.
+func (k *continuation) result(v syntax.Sexpr, err error) (state, error) {
+	if err != nil {
+		return state{}, err
+	}
+	return pass(v, k)
+}
+
+// size returns the number of frames of a continuation.
+func (k *continuation) size() int {
+	if k == nil {
+		return 0
+	}
+	return k.depth
+}
+
+// A frame receives the value of an expression with the continuation
+// after it and returns the next state of the machine.
+type frame interface {
+	resume(v syntax.Sexpr, k *continuation) (state, error)
+}
+
+// A catcher is a frame which can continue after some errors raised
+// inside of it. It reports whether it handles the error.
+type catcher interface {
+	frame
+	catch(err error, k *continuation) (state, bool, error)
+}
+
+// An exiter is a frame with something to do when it is left by an error
+// or by resuming another continuation.
+type exiter interface {
+	frame
+	exit(k *continuation) (state, error)
+}
+
+// An enterer is a frame with something to do when it is entered again
+// by resuming a continuation.
+type enterer interface {
+	frame
+	enter(k *continuation) (state, error)
+}
+
+// A thenFrame calls a Go function with the value.
+type thenFrame func(syntax.Sexpr, *continuation) (state, error)
+
+func (f thenFrame) resume(v syntax.Sexpr, k *continuation) (state, error) {
+	return f(v, k)
+}
+
+// A passFrame passes the value on. It is embedded by frames which only
+// matter when they are left or entered.
+type passFrame struct{}
+
+func (passFrame) resume(v syntax.Sexpr, k *continuation) (state, error) {
+	return pass(v, k)
+}
+
+// A boundary is the first frame of a run, the machine returns the value
+// passed to it from the run. Only a continuation of a run which is still
+// running, or of an earlier run of the REPL, is resumed, so a value
+// passed to a boundary always ends the run passing it.
+type boundary struct {
+	passFrame
+	run *machine
+}
+
+// unwind looks for a frame of k handling err and returns the state it
+// continues with. Frames left on the way which have something to do on
+// exit do it first. It reports false when no frame of the machine
+// handles the error.
+func (m *machine) unwind(err error, k *continuation) (state, bool, error) {
+	for c := k; c != nil; c = c.next {
+		switch f := c.frame.(type) {
+		case *boundary:
+			if f.run == m {
+				return state{}, false, err
+			}
+		case catcher:
+			if st, ok, err := f.catch(err, c.next); ok {
+				return st, true, err
+			}
+		case exiter:
+			st, err := f.exit(c.next.then(func(syntax.Sexpr, *continuation) (state, error) {
+				return state{}, err
+			}))
+			return st, true, err
+		}
+	}
+
+	return state{}, false, err
+}
+
+// An escape is returned by a run when a continuation resumed inside of
+// it continues outside of it. The run which started it continues the
+// jump once the Go code between them returned.
+type escape struct {
+	run   *machine
+	to    *continuation
+	value syntax.Sexpr
+}
+
+func (e *escape) Error() string {
+	return "continuation resumed outside of its run"
+}
+
+// jump resumes the continuation to with a value in place of the
+// continuation from. The frames left do their exit actions innermost
+// first and the frames entered do their entry actions outermost first.
+func (m *machine) jump(from, to *continuation, v syntax.Sexpr) (state, error) {
+	common := commonAncestor(from, to)
+
+	for c := from; c != common; c = c.next {
+		switch f := c.frame.(type) {
+		case *boundary:
+			if f.run == m && m.parent != nil {
+				return state{}, &escape{run: m, to: to, value: v}
+			}
+		case exiter:
+			return f.exit(c.next.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+				return jump(k, to, v)
+			}))
+		}
+	}
+
+	var entered *continuation
+
+	for c := to; c != common; c = c.next {
+		if _, ok := c.frame.(enterer); ok {
+			entered = c
+		}
+	}
+
+	if entered != nil {
+		return entered.frame.(enterer).enter(entered.next.then(func(syntax.Sexpr, *continuation) (state, error) {
+			return jump(entered, to, v)
+		}))
+	}
+
+	return pass(v, to)
+}
+
+// commonAncestor returns the longest continuation both a and b end with.
+func commonAncestor(a, b *continuation) *continuation {
+	for a.size() > b.size() {
+		a = a.next
+	}
+
+	for b.size() > a.size() {
+		b = b.next
+	}
+
+	for a != b {
+		a, b = a.next, b.next
+	}
+
+	return a
+}
+
+// resumable reports whether a continuation can be resumed in the thread
+// of a scope. The run it was captured in has to be still running, or be
+// a run of the REPL which ended: resuming it ends the expression being
+// evaluated like the REPL would have done.
+func resumable(s *scope.Scope, k *continuation) bool {
+	for ; k != nil; k = k.next {
+		b, ok := k.frame.(*boundary)
+
+		if !ok {
+			continue
+		}
+
+		if b.run.parent == nil {
+			return true
+		}
+
+		for m := threadOf(s).running; m != nil; m = m.parent {
+			if m == b.run {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// A continuationExpr is a continuation captured by call/cc. Calling it
+// resumes the continuation with its argument.
+type continuationExpr struct {
+	k *continuation
+}
+
+// Expr is use to satified Sexpr interface
+func (*continuationExpr) Expr() {}
+func (*continuationExpr) String() string {
+	return "#<continuation>"
+}
+
+// call resumes the continuation with its optional argument in place of
+// k. The error for a continuation which can not be resumed is raised in
+// k so the code calling it can handle it.
+func (c *continuationExpr) call(s *scope.Scope, args []syntax.Sexpr, k *continuation) (state, error) {
+	if len(args) > 1 {
+		return state{}, fmt.Errorf("continuation takes at most 1 arguments got: %d", len(args))
+	}
+
+	if !resumable(s, c.k) {
+		return state{}, fmt.Errorf("continuation can not be resumed after the built-in function it was captured in returned")
+	}
+
+	var v syntax.Sexpr = &syntax.NilExpr{}
+
+	if len(args) == 1 {
+		v = args[0]
+	}
+
+	return jump(k, c.k, v)
+}
+
+// A controlFunction is a built-in function which needs the continuation
+// of its call.
+type controlFunction func(s *scope.Scope, args []syntax.Sexpr, k *continuation) (state, error)
+
+// controlFunctions holds the functions the machine calls with their
+// continuation.
+var controlFunctions = make(map[*scope.FuncExpr]controlFunction)
+
+// newControlFunction returns a function which is called with its
+// continuation by the machine and in a new run by Go code.
+func newControlFunction(name string, control controlFunction) *scope.FuncExpr {
+	f := &scope.FuncExpr{Name: name}
+	f.Fn = func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		return callFunction(s, f, args)
+	}
+
+	controlFunctions[f] = control
+	return f
+}
+
+var (
+	callCC      = newControlFunction("call/cc", controlCallCC)
+	dynamicWind = newControlFunction("dynamic-wind", controlDynamicWind)
+	funcall     = newControlFunction("funcall", controlFuncall)
+	applyList   = newControlFunction("apply", controlApply)
+)
+
+// controlCallCC calls a function with the continuation of the call.
+// (call/cc (lambda (k) (k 1)))
+func controlCallCC(s *scope.Scope, args []syntax.Sexpr, k *continuation) (state, error) {
+	if len(args) != 1 {
+		return state{}, fmt.Errorf("call/cc needs one argument")
+	}
+
+	fn, err := functionValue(s, args[0])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	return applyFunction(s, fn, []syntax.Sexpr{&continuationExpr{k}}, k)
+}
+
+// A windFrame is the frame of the thunk of dynamic-wind. The after thunk
+// is called when the thunk returns or is left and the before thunk when
+// it is entered again.
+type windFrame struct {
+	s             *scope.Scope
+	before, after syntax.Sexpr
+}
+
+func (f *windFrame) resume(v syntax.Sexpr, k *continuation) (state, error) {
+	return applyFunction(f.s, f.after, nil, k.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+		return pass(v, k)
+	}))
+}
+
+func (f *windFrame) exit(k *continuation) (state, error) {
+	return applyFunction(f.s, f.after, nil, k)
+}
+
+func (f *windFrame) enter(k *continuation) (state, error) {
+	return applyFunction(f.s, f.before, nil, k)
+}
+
+// controlDynamicWind calls before, thunk and after in order. Whenever a
+// continuation leaves thunk after is called and whenever one enters it
+// again before is called.
+// (dynamic-wind (lambda () (print "in")) (lambda () 1) (lambda () (print "out")))
+func controlDynamicWind(s *scope.Scope, args []syntax.Sexpr, k *continuation) (state, error) {
+	if len(args) != 3 {
+		return state{}, fmt.Errorf("dynamic-wind needs three functions")
+	}
+
+	fns := make([]syntax.Sexpr, len(args))
+
+	for i, arg := range args {
+		fn, err := functionValue(s, arg)
+
+		if err != nil {
+			return state{}, err
+		}
+
+		fns[i] = fn
+	}
+
+	before, thunk, after := fns[0], fns[1], fns[2]
+
+	return applyFunction(s, before, nil, k.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+		return applyFunction(s, thunk, nil, k.push(&windFrame{s: s, before: before, after: after}))
+	}))
+}
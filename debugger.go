@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// A console is the debugger of the REPL. It reads from the same input
+// as the REPL.
+type console struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+// debug prints an error with its numbered restarts and invokes the
+// restart chosen by its number or its name. The end of the input
+// aborts.
+func (c *console) debug(s *scope.Scope, err error, restarts []*restart) (syntax.Sexpr, error) {
+	fmt.Fprintf(c.out, "Error: %s\nRestarts:\n", err)
+
+	for i, r := range restarts {
+		fmt.Fprintf(c.out, "  %d: [%s] %s\n", i, r.name.Name, r.description)
+	}
+
+	abort := restarts[len(restarts)-1]
+
+	for {
+		fmt.Fprint(c.out, "debug> ")
+
+		if !c.scanner.Scan() {
+			return abort.invoke(nil)
+		}
+
+		r := chooseRestart(restarts, strings.TrimSpace(c.scanner.Text()))
+
+		if r == nil {
+			fmt.Fprintf(c.out, "Choose a restart from 0 to %d\n", len(restarts)-1)
+			continue
+		}
+
+		args, ok := c.readValues(s, r.params)
+
+		if !ok {
+			return abort.invoke(nil)
+		}
+
+		return r.invoke(args)
+	}
+}
+
+// chooseRestart returns the restart with a number or a name or nil when
+// there is none.
+func chooseRestart(restarts []*restart, choice string) *restart {
+	if i, err := strconv.Atoi(choice); err == nil {
+		if i >= 0 && i < len(restarts) {
+			return restarts[i]
+		}
+		return nil
+	}
+
+	for _, r := range restarts {
+		if r.name.Name == choice {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// readValues reads and evaluates the arguments of a restart, one per
+// line. The debugger is disabled while they are evaluated. It returns
+// false at the end of the input.
+func (c *console) readValues(s *scope.Scope, n int) ([]syntax.Sexpr, bool) {
+	t := threadOf(s)
+	t.debugger = nil
+	defer func() { t.debugger = c.debug }()
+
+	values := make([]syntax.Sexpr, 0, n)
+
+	for len(values) < n {
+		fmt.Fprint(c.out, "value: ")
+
+		if !c.scanner.Scan() {
+			return nil, false
+		}
+
+		value, err := readValue(s, c.scanner.Text())
+
+		if err != nil {
+			fmt.Fprintln(c.out, err)
+			continue
+		}
+
+		values = append(values, value)
+	}
+
+	return values, true
+}
+
+// readValue parses and evaluates a single expression.
+func readValue(s *scope.Scope, text string) (syntax.Sexpr, error) {
+	expressions, err := parse(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expressions) != 1 {
+		return nil, fmt.Errorf("Enter one expression")
+	}
+
+	return eval(expressions[0], s)
+}
@@ -1,37 +1,350 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/miguel250/lisp-interpreter/scope"
 	"github.com/miguel250/lisp-interpreter/syntax"
 )
 
-// eval evaluate s-expressions by looking in the current scope
-// or by running a function.
-func eval(e syntax.Sexpr, s *scope.Scope) (syntax.Sexpr, error) {
-	switch e := e.(type) {
-	case *syntax.ConsExpr:
-		car, err := eval(e.Car, s)
+// specialForm is called with its arguments unevaluated so it can decide
+// when and if each one gets evaluated. It returns the next state of the
+// machine, usually the evaluation of one of its arguments with a frame
+// pushed on k which receives the value. S-expressions in tail position
+// are evaluated with k itself so they run in constant space.
+type specialForm func(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error)
+
+// specialForms holds all special forms by name. It is filled by init in
+// special.go to avoid an initialization loop with eval.
+var specialForms map[string]specialForm
+
+// stateKind is what the machine does with a state.
+type stateKind int
+
+const (
+	evalState   stateKind = iota // evaluate expr in scope
+	returnState                  // pass value to the first frame of k
+	jumpState                    // resume k with value in place of from
+)
+
+// A state is the next step of the machine: an expression to evaluate
+// or a value to pass to a continuation.
+type state struct {
+	kind  stateKind
+	expr  syntax.Sexpr
+	scope *scope.Scope
+	value syntax.Sexpr
+	from  *continuation
+	k     *continuation
+}
+
+// evaluate returns the state evaluating e in s and passing its value
+// to k.
+func evaluate(e syntax.Sexpr, s *scope.Scope, k *continuation) (state, error) {
+	return state{kind: evalState, expr: e, scope: s, k: k}, nil
+}
+
+// pass returns the state passing v to k.
+func pass(v syntax.Sexpr, k *continuation) (state, error) {
+	return state{kind: returnState, value: v, k: k}, nil
+}
+
+// jump returns the state resuming the continuation to with a value in
+// place of the continuation from.
+func jump(from, to *continuation, v syntax.Sexpr) (state, error) {
+	return state{kind: jumpState, value: v, from: from, k: to}, nil
+}
 
+// A machine runs states until its continuation returns a value. Go
+// code like built-in functions and macros calling back into Lisp
+// starts a new run on top of the one running them.
+type machine struct {
+	parent *machine
+	k      *continuation // continuation of the current step
+}
+
+// A thread is the state of the evaluation of the code of one global
+// scope. It is kept in the context of the scope so code evaluated in
+// other global scopes, maybe at the same time, does not share it.
+type thread struct {
+	running  *machine                               // innermost machine running or nil
+	debugger debugger                               // nil when the debugger is not enabled
+	declared map[*syntax.SymbolExpr]bool            // special variables
+	specials map[*syntax.SymbolExpr][]*specialFrame // dynamic bindings innermost last
+}
+
+// threadOf returns the thread evaluating the code of a scope.
+func threadOf(s *scope.Scope) *thread {
+	t, ok := s.Context().(*thread)
+
+	if !ok {
+		t = &thread{
+			declared: map[*syntax.SymbolExpr]bool{printCircleSymbol: true},
+			specials: make(map[*syntax.SymbolExpr][]*specialFrame),
+		}
+		s.SetContext(t)
+	}
+
+	return t
+}
+
+// currentContinuation returns the continuation of the step being run
+// in the thread of a scope or nil outside of the machine.
+func currentContinuation(s *scope.Scope) *continuation {
+	t := threadOf(s)
+
+	if t.running == nil {
+		return nil
+	}
+	return t.running.k
+}
+
+// run starts a new machine on top of the one running in the thread of s
+// with the state returned by start and returns the value of its
+// continuation.
+func run(s *scope.Scope, start func(k *continuation) (state, error)) (syntax.Sexpr, error) {
+	t := threadOf(s)
+	m := &machine{parent: t.running}
+	m.k = currentContinuation(s).push(&boundary{run: m})
+
+	t.running = m
+	defer func() { t.running = m.parent }()
+
+	st, err := start(m.k)
+
+	for {
 		if err != nil {
-			return nil, err
+			if st, err = m.recover(err); err != nil {
+				return nil, err
+			}
+		}
+
+		switch st.kind {
+		case evalState:
+			m.k = st.k
+			st, err = step(st.expr, st.scope, st.k)
+		case returnState:
+			m.k = st.k.next
+
+			if _, ok := st.k.frame.(*boundary); ok {
+				return st.value, nil
+			}
+
+			st, err = st.k.frame.resume(st.value, st.k.next)
+		case jumpState:
+			m.k = st.from
+			st, err = m.jump(st.from, st.k, st.value)
+		}
+	}
+}
+
+// recover returns the state to continue with after an error. An escape
+// from a run started by this one continues its jump here, other errors
+// unwind the continuation to a frame which can handle them. The error is
+// returned when the run has to stop.
+func (m *machine) recover(err error) (state, error) {
+	if e, ok := err.(*escape); ok {
+		if e.run == m {
+			return state{}, err
+		}
+		return jump(m.k, e.to, e.value)
+	}
+
+	st, handled, err := m.unwind(err, m.k)
+
+	if !handled {
+		return state{}, err
+	}
+
+	return st, err
+}
+
+// eval evaluates a s-expression in a new run of the machine and returns
+// its value. It is used by Go code which needs the value right away.
+func eval(e syntax.Sexpr, s *scope.Scope) (syntax.Sexpr, error) {
+	return run(s, func(k *continuation) (state, error) {
+		return evaluate(e, s, k)
+	})
+}
+
+// step evaluates a s-expression by looking in the current scope or by
+// running a special form, a macro or a function.
+func step(e syntax.Sexpr, s *scope.Scope, k *continuation) (state, error) {
+	cons, ok := e.(*syntax.ConsExpr)
+
+	if !ok {
+		if symbol, ok := e.(*syntax.SymbolExpr); ok {
+			return k.result(evalSymbol(symbol, s))
+		}
+		return pass(e, k)
+	}
+
+	// make sure we have a list of arguments ready to
+	// pass to function.
+	args, err := listToSlice(cons.Cdr)
+
+	if err != nil {
+		return state{}, err
+	}
+
+	if symbol, ok := cons.Car.(*syntax.SymbolExpr); ok {
+		if form, ok := specialForms[symbol.Name]; ok {
+			return form(s, args, k)
+		}
+	}
+
+	return evaluate(cons.Car, s, k.then(func(car syntax.Sexpr, k *continuation) (state, error) {
+		// macros are expanded with the arguments unevaluated and the
+		// expansion is evaluated in their place.
+		if m, ok := car.(*scope.MacroExpr); ok {
+			expansion, err := m.Fn(s, args)
+
+			if err != nil {
+				return state{}, err
+			}
+
+			return evaluate(expansion, s, k)
 		}
 
-		args := make([]syntax.Sexpr, 0, 0)
+		if !isFunction(car) {
+			return k.result(invokeDebugger(s, fmt.Errorf("Unable to call expression as function: {%s}", car)))
+		}
 
-		cdr, ok := e.Cdr.(*syntax.ConsExpr)
+		return evalArgs(s, args, nil, k, func(args []syntax.Sexpr, k *continuation) (state, error) {
+			return applyFunction(s, car, args, k)
+		})
+	}))
+}
 
-		// make sure we have a list of arguments ready to
-		// pass to function.
-		for ok {
-			args = append(args, cdr.Car)
-			cdr, ok = cdr.Cdr.(*syntax.ConsExpr)
+// applyFunction calls a function or a continuation with arguments
+// that are already evaluated and passes its value to k.
+func applyFunction(s *scope.Scope, fn syntax.Sexpr, args []syntax.Sexpr, k *continuation) (state, error) {
+	switch f := fn.(type) {
+	case *continuationExpr:
+		return f.call(s, args, k)
+	case *scope.FuncExpr:
+		if control, ok := controlFunctions[f]; ok {
+			return control(s, args, k)
 		}
 
-		f := car.(*scope.FuncExpr)
-		// call function with arguments
+		if f.Lambda == nil {
+			// call function with arguments
+			return k.result(callBuiltin(s, f, args))
+		}
+
+		local, bound, err := bindParams(f.Name, f.Lambda, args, k)
+
+		if err != nil {
+			return k.result(invokeDebugger(s, err))
+		}
+
+		return enterBindings(k, bound, func(k *continuation) (state, error) {
+			return evalSequence(f.Lambda.Body, local, k)
+		})
+	}
+
+	return k.result(invokeDebugger(s, fmt.Errorf("Unable to call expression as function: {%s}", fn)))
+}
+
+// evalSymbol returns the value of a symbol. Keywords evaluate to
+// themselves.
+func evalSymbol(symbol *syntax.SymbolExpr, s *scope.Scope) (syntax.Sexpr, error) {
+	if symbol.IsKeyword() {
+		return symbol, nil
+	}
+
+	value, err := symbolValue(s, symbol)
+
+	if err != nil {
+		return invokeDebugger(s, err, unboundRestarts(s, symbol)...)
+	}
+
+	return value, nil
+}
+
+// callBuiltin calls a built-in function. When it fails the debugger can
+// use another value or call it again.
+func callBuiltin(s *scope.Scope, f *scope.FuncExpr, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	result, err := f.Fn(s, args)
+
+	if err != nil {
+		return invokeDebugger(s, err, callRestarts(s, f, args, err)...)
+	}
+
+	return result, nil
+}
+
+// evalSequence evaluates a body in order and passes the value of the
+// last s-expression to k, it is evaluated in tail position. An empty
+// body passes nil.
+func evalSequence(body []syntax.Sexpr, s *scope.Scope, k *continuation) (state, error) {
+	switch len(body) {
+	case 0:
+		return pass(&syntax.NilExpr{}, k)
+	case 1:
+		return evaluate(body[0], s, k)
+	}
+
+	return evaluate(body[0], s, k.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+		return evalSequence(body[1:], s, k)
+	}))
+}
+
+// evalArgs evaluates the s-expressions ss in order and calls then with
+// their values appended to args.
+func evalArgs(s *scope.Scope, ss, args []syntax.Sexpr, k *continuation, then func([]syntax.Sexpr, *continuation) (state, error)) (state, error) {
+	if len(ss) == 0 {
+		return then(args, k)
+	}
+
+	return evaluate(ss[0], s, k.then(func(v syntax.Sexpr, k *continuation) (state, error) {
+		// the full slice expression makes append copy args so a
+		// continuation resumed twice does not share them.
+		return evalArgs(s, ss[1:], append(args[:len(args):len(args)], v), k, then)
+	}))
+}
+
+// functionValue returns the function bound to a symbol or fn itself.
+func functionValue(s *scope.Scope, fn syntax.Sexpr) (syntax.Sexpr, error) {
+	if symbol, ok := fn.(*syntax.SymbolExpr); ok {
+		return s.Get(symbol)
+	}
+	return fn, nil
+}
+
+// apply calls a function value with arguments that are already
+// evaluated. Built-in functions and closures go through the same path and
+// a symbol is called with the function it is bound to.
+func apply(s *scope.Scope, fn syntax.Sexpr, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	fn, err := functionValue(s, fn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch f := fn.(type) {
+	case *scope.FuncExpr:
 		return f.Fn(s, args)
-	case *syntax.SymbolExpr:
-		return s.Get(*e)
+	case *continuationExpr:
+		return callFunction(s, f, args)
+	}
+
+	return nil, fmt.Errorf("Unable to call expression as function: {%s}", fn)
+}
+
+// callFunction calls a function from Go in a new run of the machine.
+func callFunction(s *scope.Scope, fn syntax.Sexpr, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	return run(s, func(k *continuation) (state, error) {
+		return applyFunction(s, fn, args, k)
+	})
+}
+
+// isTrue reports whether a s-expression counts as true. Only nil is false,
+// every other value including 0 and "" is true.
+func isTrue(e syntax.Sexpr) bool {
+	switch e.(type) {
+	case nil, *syntax.NilExpr:
+		return false
 	}
-	return e, nil
+	return true
 }
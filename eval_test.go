@@ -1,11 +1,45 @@
 package main
 
 import (
+	"fmt"
+	"runtime/debug"
+	"sync"
 	"testing"
 
 	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
 )
 
+// newTestScope returns a scope with all built-in functions added.
+func newTestScope() *scope.Scope {
+	s := scope.NewScope(nil)
+
+	b := newBuiltins()
+	for k, v := range b.fn {
+		s.Set(k, v)
+	}
+	return s
+}
+
+// evalString evaluates every s-expression in input and returns
+// the last result.
+func evalString(input string, s *scope.Scope) (syntax.Sexpr, error) {
+	expr, err := parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var e syntax.Sexpr
+	for _, ex := range expr {
+		e, err = eval(ex, s)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
 func TestEval(t *testing.T) {
 
 	for _, test := range []struct {
@@ -18,21 +52,893 @@ func TestEval(t *testing.T) {
 		{`(first (list 4 5 6))`, "4"},
 		{`(+ 1 2)`, "3"},
 		{`(+ 1.4 5.0)`, "6.4"},
+		{`(+ 1 2.5)`, "3.5"},
+		{`(+ -7 010)`, "3"},
+		{`(- -1/2)`, "1/2"},
+		{`(+ 2.5 1)`, "3.5"},
+		{`(+ 9223372036854775807 1)`, "9223372036854775808"},
+		{`(+ 9223372036854775808 0.5)`, "9.223372036854776e+18"},
+		{`(+ 1/3 1/6)`, "1/2"},
+		{`(+ 1/2 1/2)`, "1"},
+		{`(+ 1/2 1)`, "3/2"},
+		{`(+ 1/2 0.25)`, "0.75"},
+		{`(+ 1/2 99999999999999999999)`, "199999999999999999999/2"},
+		{`(+)`, "0"},
+		{`(+ 1 2 3 4)`, "10"},
+		{`(*)`, "1"},
+		{`(* 2 3 4)`, "24"},
+		{`(* 4294967296 4294967296)`, "18446744073709551616"},
+		{`(- 5)`, "-5"},
+		{`(- 10 1 2)`, "7"},
+		{`(- 1 0.5)`, "0.5"},
+		{`(/ 2)`, "1/2"},
+		{`(/ 12 2 3)`, "2"},
+		{`(/ 1 3)`, "1/3"},
+		{`(/ 1.0 4)`, "0.25"},
+		{`(mod 7 3)`, "1"},
+		{`(mod (- 7) 3)`, "2"},
+		{`(mod 7 (- 3))`, "-2"},
+		{`(rem (- 7) 3)`, "-1"},
+		{`(mod -7 3)`, "2"},
+		{`(rem -7 3)`, "-1"},
+		{`(mod 7 -3)`, "-2"},
+		{`(quotient (- 7) 2)`, "-3"},
+		{`(abs (- 3))`, "3"},
+		{`(abs (- 1/2))`, "1/2"},
+		{`(abs 2.5)`, "2.5"},
+		{`(min 3 1 2)`, "1"},
+		{`(max 3 1.5 2)`, "3"},
+		{`(max 1/2 0.25)`, "1/2"},
+		{`(= 1 1.0 2/2)`, "t"},
+		{`(= 1 2)`, "nil"},
+		{`(< 1 2 3)`, "t"},
+		{`(< 1 3 2)`, "nil"},
+		{`(> 3 2.5 1/2)`, "t"},
+		{`(<= 1 1 2)`, "t"},
+		{`(>= 2 2 3)`, "nil"},
+		{`(< 99999999999999999999 100000000000000000000)`, "t"},
+		{`(/= 1 2 3)`, "t"},
+		{`(/= 1 2 1)`, "nil"},
+		{`(zerop 0.0)`, "t"},
+		{`(zerop 1/2)`, "nil"},
+		{`(evenp 4)`, "t"},
+		{`(evenp (- 3))`, "nil"},
+		{`(oddp (- 3))`, "t"},
+		{`(oddp 99999999999999999999)`, "t"},
+		{`(1+ 1)`, "2"},
+		{`(1- 1/2)`, "-1/2"},
+		{`(1+ 9223372036854775807)`, "9223372036854775808"},
+		{`(expt 2 10)`, "1024"},
+		{`(expt 2 100)`, "1267650600228229401496703205376"},
+		{`(expt 2 (- 2))`, "1/4"},
+		{`(expt 1.5 2)`, "2.25"},
+		{`(expt 2/3 3)`, "8/27"},
+		{`(expt 5 0)`, "1"},
+		{`true`, "t"},
+		{`false`, "nil"},
+		{`'t`, "t"},
+		{`(if false 1 2)`, "2"},
+		{`(null nil)`, "t"},
+		{`(null 0)`, "nil"},
+		{`(not t)`, "nil"},
+		{`(atom 1)`, "t"},
+		{`(atom nil)`, "t"},
+		{`(atom '(1))`, "nil"},
+		{`(consp '(1))`, "t"},
+		{`(consp nil)`, "nil"},
+		{`(listp nil)`, "t"},
+		{`(listp '(1))`, "t"},
+		{`(listp 1)`, "nil"},
+		{`(symbolp 'x)`, "t"},
+		{`(symbolp nil)`, "t"},
+		{`(symbolp t)`, "t"},
+		{`(symbolp "x")`, "nil"},
+		{`(numberp 1/2)`, "t"},
+		{`(numberp "1")`, "nil"},
+		{`(integerp 99999999999999999999)`, "t"},
+		{`(integerp 1.0)`, "nil"},
+		{`(floatp 1.0)`, "t"},
+		{`(floatp 1)`, "nil"},
+		{`(stringp "x")`, "t"},
+		{`(stringp 'x)`, "nil"},
+		{`(functionp +)`, "t"},
+		{`(functionp (lambda ()))`, "t"},
+		{`(functionp 'x)`, "nil"},
+		{`(type-of nil)`, "null"},
+		{`(type-of t)`, "boolean"},
+		{`(type-of '(1))`, "cons"},
+		{`(type-of 'x)`, "symbol"},
+		{`(type-of 1)`, "integer"},
+		{`(type-of 99999999999999999999)`, "integer"},
+		{`(type-of 1/2)`, "ratio"},
+		{`(type-of 1.5)`, "float"},
+		{`(type-of "x")`, "string"},
+		{`(type-of +)`, "function"},
+		{"(defmacro m () 1)\n(type-of m)", "macro"},
+		{"(let ((x 1)) (defmacro twice (e) `(list ,e ,e)))\n(twice 2)", "(cons 2 (cons 2 nil))"},
+		{`(symbolp (type-of 1))`, "t"},
+		{"(defun fact (n) (if (= n 0) 1 (* n (fact (- n 1)))))\n(fact 25)", "15511210043330985984000000"},
+		{`(lambda (x y) x)`, "fn: lambda (x y)"},
+		{`(lambda (x &optional y &rest z))`, "fn: lambda (x &optional y &rest z)"},
+		{`((lambda (x) (+ x 1)) 2)`, "3"},
+		{`((lambda ()))`, "nil"},
+		{`(defun add (x y) (+ x y))`, "add"},
+		{"(defun add (x y) (+ x y))\n(add 1 2)", "3"},
+		{"(let ((n 1)) (defun add-n (x) (+ x n)))\n(add-n 2)", "3"},
+		{"(defun outer () (defun inner () 'inner))\n(outer)\n(inner)", "inner"},
+		{"(defun add (x y) (+ x y))\nadd", "fn: add (x y)"},
+		{"(defun f (x &optional y) y)\n(f 1)", "nil"},
+		{"(defun f (x &optional y) y)\n(f 1 2)", "2"},
+		{"(defun f (x &rest r) r)\n(f 1 2 3)", "(cons 2 (cons 3 nil))"},
+		{"(defun adder (n) (lambda (x) (+ x n)))\n(setq add2 (adder 2))\n(add2 5)", "7"},
+		{"(setq x 1)\n(defun f (x) x)\n(f 2)\nx", "1"},
+		{`nil`, "nil"},
+		{`t`, "t"},
+		{`(if 0 "yes" "no")`, "\"yes\""},
+		{`(if () "yes" "no")`, "\"no\""},
+		{`(if nil "yes")`, "nil"},
+		{`(if "" (+ 1 2) undefined)`, "3"},
+		{`(cond (nil 1) ((list 2) 2) (t undefined))`, "2"},
+		{`(cond (nil 1) ((first (list 7))))`, "7"},
+		{`(cond (nil 1))`, "nil"},
+		{`(when t 1 2)`, "2"},
+		{`(when nil undefined)`, "nil"},
+		{`(unless nil 1 2)`, "2"},
+		{`(unless t undefined)`, "nil"},
+		{`(and)`, "t"},
+		{`(and 1 2 3)`, "3"},
+		{`(and 1 nil undefined)`, "nil"},
+		{`(or)`, "nil"},
+		{`(or nil 2 undefined)`, "2"},
+		{"(defun fact (n) (if (first (list n)) n 1))\n(fact 4)", "4"},
+		{`(let ((x 1) (y 2)) (+ x y))`, "3"},
+		{`(let (x (y)) (list x y))`, "(cons nil (cons nil nil))"},
+		{"(setq x 1)\n(let ((x 2) (y x)) y)", "1"},
+		{"(setq x 1)\n(let ((x 2)) x)\nx", "1"},
+		{"(let ((x 1)) (setq y 2))\ny", "2"},
+		{"(setq x 1)\n(let ((x 2)) (setq x 3))\nx", "1"},
+		{"(setq x 1)\n(let () (setq x 3))\nx", "3"},
+		{`(let* ((x 1) (y (+ x 1))) y)`, "2"},
+		{`(let* ((x 1) (x (+ x 1))) x)`, "2"},
+		{`(letrec ((f (lambda (n) (if n (g n) 0))) (g (lambda (n) (f nil)))) (f 1))`, "0"},
+		{`(flet ((double (x) (+ x x))) (double 2))`, "4"},
+		{"(defun double (x) x)\n(flet ((double (x) (+ x x)) (quad (x) (double x))) (quad 2))", "2"},
+		{`(labels ((double (x) (+ x x)) (quad (x) (double (double x)))) (quad 2))`, "8"},
+		{"(flet ((double (x) (+ x x))) 1)\n(setq double 3)", "3"},
+		{`'x`, "x"},
+		{`'(1 x "y")`, "(cons 1 (cons x (cons \"y\" nil)))"},
+		{`(quote (+ 1 2))`, "(cons + (cons 1 (cons 2 nil)))"},
+		{`''x`, "(cons quote (cons x nil))"},
+		{"`x", "x"},
+		{"(setq x 2)\n`(1 ,x 3)", "(cons 1 (cons 2 (cons 3 nil)))"},
+		{"`(1 ,(+ 1 1) ,@(list 3 4) 5)", "(cons 1 (cons 2 (cons 3 (cons 4 (cons 5 nil)))))"},
+		{"`(1 ,@'())", "(cons 1 nil)"},
+		{"`(1 `(2 ,(3 ,(+ 1 3))))", "(cons 1 (cons (cons quasiquote (cons (cons 2 (cons (cons unquote (cons (cons 3 (cons 4 nil)) nil)) nil)) nil)) nil))"},
+		{"`(1 `(2 ,@(3 ,@(list 4 5))))", "(cons 1 (cons (cons quasiquote (cons (cons 2 (cons (cons unquote-splicing (cons (cons 3 (cons 4 (cons 5 nil))) nil)) nil)) nil)) nil))"},
+		{"(defmacro add (a b) `(+ ,b ,a))\n(add 1 2)", "3"},
+		{"(defmacro add (a b) `(+ ,b ,a))\nadd", "macro: add (a b)"},
+		{"(defmacro my-unless (c &body body) `(if ,c nil (let () ,@body)))\n(my-unless nil 1 2)", "2"},
+		{"(defmacro inc (x) `(setq ,x (+ ,x 1)))\n(setq n 1)\n(inc n)\nn", "2"},
+		{"(defmacro inc (x) `(setq ,x (+ ,x 1)))\n(macroexpand-1 '(inc n))", "(cons setq (cons n (cons (cons + (cons n (cons 1 nil))) nil)))"},
+		{"(defmacro a (x) `(b ,x))\n(defmacro b (x) `(+ ,x 1))\n(macroexpand-1 '(a 1))", "(cons b (cons 1 nil))"},
+		{"(defmacro a (x) `(b ,x))\n(defmacro b (x) `(+ ,x 1))\n(macroexpand '(a 1))", "(cons + (cons 1 (cons 1 nil)))"},
+		{"(defmacro a (x) `(b ,x))\n(defmacro b (x) `(+ ,x 1))\n(a 1)", "2"},
+		{`(macroexpand '(+ 1 2))`, "(cons + (cons 1 (cons 2 nil)))"},
+		{`(macroexpand 'x)`, "x"},
+		{"(define-syntax swap (syntax-rules () ((_ a b) (let ((tmp a)) (setq a b) (setq b tmp)))))\n(setq tmp 1)\n(setq y 2)\n(swap tmp y)\n(list tmp y)", "(cons 2 (cons 1 nil))"},
+		{"(define-syntax my-or (syntax-rules () ((_) nil) ((_ e) e) ((_ e r ...) (let ((tmp e)) (if tmp tmp (my-or r ...))))))\n(let ((tmp 5)) (my-or nil tmp))", "5"},
+		{"(define-syntax my-or (syntax-rules () ((_) nil) ((_ e) e) ((_ e r ...) (let ((tmp e)) (if tmp tmp (my-or r ...))))))\n(my-or nil nil 3)", "3"},
+		{"(define-syntax sums (syntax-rules () ((_ (a b) ...) (list (+ a b) ...))))\n(sums (1 2) (3 4))", "(cons 3 (cons 7 nil))"},
+		{"(define-syntax sums (syntax-rules () ((_ (a b) ...) (list (+ a b) ...))))\n(macroexpand '(sums))", "(cons list nil)"},
+		{"(define-syntax nest (syntax-rules () ((_ (a ...) ...) '((a ...) ...))))\n(nest (1 2) (3))", "(cons (cons 1 (cons 2 nil)) (cons (cons 3 nil) nil))"},
+		{"(define-syntax nest (syntax-rules () ((_ (a ...) ...) (list (list a ...) ...))))\n(nest (1 2) (3))", "(cons (cons 1 (cons 2 nil)) (cons (cons 3 nil) nil))"},
+		{"(define-syntax last (syntax-rules () ((_ a ... b) b)))\n(last 1 2 3)", "3"},
+		{"(define-syntax arrow (syntax-rules (=>) ((_ a => b) (list a b)) ((_ a b) b)))\n(arrow 1 => 2)", "(cons 1 (cons 2 nil))"},
+		{"(define-syntax arrow (syntax-rules (=>) ((_ a => b) (list a b)) ((_ a b) b)))\n(arrow 1 2)", "2"},
+		{"(let ((x 1)) (define-syntax first-of (syntax-rules () ((_ a b) a))))\n(first-of 1 2)", "1"},
+		{"(define-syntax set-free (syntax-rules () ((_ v) (setq free-var v))))\n(set-free 3)\nfree-var", "3"},
+		{"(define-syntax one (syntax-rules () ((_ 1 a) a) ((_ b a) b)))\n(list (one 1 2) (one 3 4))", "(cons 2 (cons 3 nil))"},
+		{"(define-syntax bump (syntax-rules () ((_) (setq counter (+ counter 1)))))\n(setq counter 1)\n(bump)\ncounter", "2"},
+		{"(define-syntax sym (syntax-rules () ((_) 'foo)))\n(sym)", "foo"},
+		{"(define-syntax swap (syntax-rules () ((_ a b) (let ((tmp a)) (setq a b) (setq b tmp)))))\nswap", "macro: swap"},
+		{`(progn)`, "nil"},
+		{"(progn (setq x 1) (setq x (+ x 1)) x)", "2"},
+		{"(begin 1 2)", "2"},
+		{"(defun counter () (let ((n 0)) (lambda () (setq n (+ n 1)))))\n(setq c (counter))\n(c)\n(c)", "2"},
+		{`(list)`, "nil"},
+		{`(cons 1 '(2))`, "(cons 1 (cons 2 nil))"},
+		{`(car '(1 2))`, "1"},
+		{`(car nil)`, "nil"},
+		{`(cdr '(1 2))`, "(cons 2 nil)"},
+		{`(rest nil)`, "nil"},
+		{`(cadr '(1 2 3))`, "2"},
+		{`(cddr '(1 2 3))`, "(cons 3 nil)"},
+		{`(caar '((1) 2))`, "1"},
+		{`(cadddr '(1 2 3 4))`, "4"},
+		{`(nth 2 '(1 2 3))`, "3"},
+		{`(nth 5 '(1 2 3))`, "nil"},
+		{`(nth 9223372036854775807 '(1))`, "nil"},
+		{`(nthcdr 9223372036854775807 '(1 2))`, "nil"},
+		{`(nthcdr 2 '(1 2 3))`, "(cons 3 nil)"},
+		{`(nthcdr 0 '(1 2 3))`, "(cons 1 (cons 2 (cons 3 nil)))"},
+		{`(last '(1 2 3))`, "(cons 3 nil)"},
+		{`(last nil)`, "nil"},
+		{`(length '(1 2 3))`, "3"},
+		{`(length nil)`, "0"},
+		{`(append '(1 2) nil '(3) '(4))`, "(cons 1 (cons 2 (cons 3 (cons 4 nil))))"},
+		{`(append)`, "nil"},
+		{`(append '(1) 2)`, "(cons 1 2)"},
+		{`(reverse '(1 2 3))`, "(cons 3 (cons 2 (cons 1 nil)))"},
+		{`(member 2 '(1 2 3))`, "(cons 2 (cons 3 nil))"},
+		{`(member 'b '(a b))`, "(cons b nil)"},
+		{`(member 1.0 '(1 2))`, "nil"},
+		{`(assoc 'b '((a 1) nil (b 2)))`, "(cons b (cons 2 nil))"},
+		{`(assoc 'c '((a 1)))`, "nil"},
+		{`(copy-list '(1 2))`, "(cons 1 (cons 2 nil))"},
+		{`(copy-list (list* 1 2))`, "(cons 1 2)"},
+		{`(list* 1 2 '(3))`, "(cons 1 (cons 2 (cons 3 nil)))"},
+		{`(list* 1 2)`, "(cons 1 2)"},
+		{`(list* 1)`, "1"},
+		{`(mapcar '1+ '(1 2 3))`, "(cons 2 (cons 3 (cons 4 nil)))"},
+		{`(mapcar + '(1 2 3) '(10 20))`, "(cons 11 (cons 22 nil))"},
+		{`(mapcar (lambda (x) (* x x)) '(1 2))`, "(cons 1 (cons 4 nil))"},
+		{"(setq n 0)\n(mapc (lambda (x) (setq n (+ n x))) '(1 2 3))\nn", "6"},
+		{`(mapc 'print nil)`, "nil"},
+		{`(list (print 1))`, "(cons 1 nil)"},
+		{`(equal (print '(a)) '(a))`, "t"},
+		{`(mapcar 'print '(1 2))`, "(cons 1 (cons 2 nil))"},
+		{`(filter 'oddp '(1 2 3))`, "(cons 1 (cons 3 nil))"},
+		{`(remove-if 'oddp '(1 2 3))`, "(cons 2 nil)"},
+		{`(remove-if-not 'oddp '(2))`, "nil"},
+		{`(reduce '+ '(1 2 3))`, "6"},
+		{`(reduce '+ '(1 2 3) 10)`, "16"},
+		{`(reduce '+ nil)`, "0"},
+		{`(reduce 'list '(1 2 3))`, "(cons (cons 1 (cons 2 nil)) (cons 3 nil))"},
+		{`(reduce '+ '(5))`, "5"},
+		{`(some 'evenp '(1 2 3))`, "t"},
+		{`(some (lambda (x) (if (> x 1) x)) '(1 2 3))`, "2"},
+		{`(some 'evenp nil)`, "nil"},
+		{`(every 'evenp '(2 4))`, "t"},
+		{`(every 'evenp '(2 3))`, "nil"},
+		{`(every '< '(1 2) '(2 3))`, "t"},
+		{`(find-if 'evenp '(1 2 3 4))`, "2"},
+		{`(find-if 'evenp '(1 3))`, "nil"},
+		{`(position 'c '(a b c))`, "2"},
+		{`(position 'd '(a b c))`, "nil"},
+		{`(funcall '+ 1 2)`, "3"},
+		{`(funcall (lambda (&rest xs) xs))`, "nil"},
+		{`(apply '+ 1 2 '(3 4))`, "10"},
+		{`(apply max '(3 1 2))`, "3"},
+		{"(defun add (x y) (+ x y))\n(apply 'add '(1 2))", "3"},
+		{`(cdr '(1 . 2))`, "2"},
+		{`(assoc 'b '((a . 1) (b . 2)))`, "(cons b 2)"},
+		{"(setq x (list 1 2))\n(setcar x 3)\nx", "(cons 3 (cons 2 nil))"},
+		{"(setq x (list 1 2))\n(set-cdr! x 3)\nx", "(cons 1 3)"},
+		{"(setq x (list 1))\n(setq y (list x x))\n(set-car! x 2)\ny", "(cons (cons 2 nil) (cons (cons 2 nil) nil))"},
+		{`(setcdr (list 1) 2)`, "2"},
+		{"(setq x (list 1 2))\n(setcdr (cdr x) x)\nx", "#1=(cons 1 (cons 2 #1#))"},
+		{"(setq x (list 1))\n(list x x)", "(cons (cons 1 nil) (cons (cons 1 nil) nil))"},
+		{`(nth 5 '#1=(1 2 . #1#))`, "2"},
+		{`'(#1=(a) #1# #1#)`, "(cons (cons a nil) (cons (cons a nil) (cons (cons a nil) nil)))"},
+		{`'#2=(a #2#)`, "#1=(cons a (cons #1# nil))"},
+		{`'(#1=x #1#)`, "(cons x (cons x nil))"},
+		{`*print-circle*`, "nil"},
+		{`(make-hash-table)`, "#<hash-table eql 0>"},
+		{`(make-hash-table 'equal)`, "#<hash-table equal 0>"},
+		{`(make-hash-table 'eq)`, "#<hash-table eq 0>"},
+		{"(setq h (make-hash-table 'eq))\n(puthash 1 'one h)\n(list (gethash 1 h) (gethash 1.0 h))", "(cons one (cons nil nil))"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(puthash 'b 2 h)\n(gethash 'b h)", "2"},
+		{"(setq h (make-hash-table))\n(puthash 1 'one h)\n(gethash 1 h)", "one"},
+		{"(setq h (make-hash-table))\n(puthash 1 'one h)\n(gethash 1.0 h)", "nil"},
+		{"(setq h (make-hash-table))\n(gethash 'a h 0)", "0"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(puthash 'a 2 h)\n(list (gethash 'a h) (hash-table-count h))", "(cons 2 (cons 1 nil))"},
+		{"(setq h (make-hash-table))\n(puthash \"a\" 1 h)\n(gethash \"a\" h)", "nil"},
+		{"(setq h (make-hash-table 'equal))\n(puthash \"a\" 1 h)\n(gethash \"a\" h)", "1"},
+		{"(setq h (make-hash-table 'equal))\n(puthash '(1 (2 \"x\")) 1 h)\n(gethash (list 1 (list 2 \"x\")) h)", "1"},
+		{"(setq h (make-hash-table 'equal))\n(puthash '(1 2) 1 h)\n(gethash '(1 2.0) h)", "nil"},
+		{"(setq h (make-hash-table 'equal))\n(puthash '#1=(1 . #1#) 1 h)\n(hash-table-count h)", "1"},
+		{"(setq h (make-hash-table))\n(setq k (list 1))\n(puthash k 1 h)\n(list (gethash k h) (gethash (list 1) h))", "(cons 1 (cons nil nil))"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(list (remhash 'a h) (remhash 'a h) (hash-table-count h))", "(cons t (cons nil (cons 0 nil)))"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(clrhash h)", "#<hash-table eql 0>"},
+		{"(setq h (make-hash-table))\n(puthash 'b 1 h)\n(puthash 'a 2 h)\n(hash-table-keys h)", "(cons b (cons a nil))"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(puthash 'b 2 h)\n(setq n 0)\n(maphash (lambda (k v) (setq n (+ n v))) h)\nn", "3"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(maphash (lambda (k v) (remhash k h)) h)\n(hash-table-count h)", "0"},
+		{`(hash-table-p (make-hash-table))`, "t"},
+		{`(type-of (make-hash-table))`, "hash-table"},
+		{`#(1 2 3)`, "#(1 2 3)"},
+		{`[a (b c) "d"]`, "#(a (cons b (cons c nil)) \"d\")"},
+		{`[]`, "#()"},
+		{`(make-vector 2 0)`, "#(0 0)"},
+		{`(make-vector 1)`, "#(nil)"},
+		{`(vector 1 (+ 1 1))`, "#(1 2)"},
+		{`(aref [1 2 3] 2)`, "3"},
+		{"(setq v (vector 1 2))\n(aset v 0 'x)\nv", "#(x 2)"},
+		{`(vector-length [1 2])`, "2"},
+		{`(length [1 2 3])`, "3"},
+		{"(setq v (vector))\n(vector-push 'a v)\n(vector-push 'b v)", "1"},
+		{"(setq v (vector))\n(vector-push 'a v)\nv", "#(a)"},
+		{`(subseq [1 2 3 4] 1 3)`, "#(2 3)"},
+		{`(subseq [1 2 3] 3)`, "#()"},
+		{`(subseq '(1 2 3) 1)`, "(cons 2 (cons 3 nil))"},
+		{"(setq v [1 2])\n(setq w (subseq v 0))\n(aset w 0 3)\nv", "#(1 2)"},
+		{`(vector-to-list [1 2])`, "(cons 1 (cons 2 nil))"},
+		{`(list-to-vector '(1 2))`, "#(1 2)"},
+		{`(vectorp [1])`, "t"},
+		{`(vectorp '(1))`, "nil"},
+		{`(type-of [])`, "vector"},
+		{"(setq v (vector 1))\n(aset v 0 v)\nv", "#1=#(#1#)"},
+		{`'#1=[a #1#]`, "#1=#(a #1#)"},
+		{`(concat "ab" "" "cd")`, "\"abcd\""},
+		{`(concat)`, "\"\""},
+		{`(string-length "héllo")`, "5"},
+		{`(substring "héllo" 1 3)`, "\"él\""},
+		{`(substring "hello" 2)`, "\"llo\""},
+		{`(substring "hello" 5)`, "\"\""},
+		{`(string-upcase "héllo")`, "\"HÉLLO\""},
+		{`(string-downcase "ÀB")`, "\"àb\""},
+		{`(string-split "a,b,,c" ",")`, "(cons \"a\" (cons \"b\" (cons \"\" (cons \"c\" nil))))"},
+		{`(string-split "  a  b ")`, "(cons \"a\" (cons \"b\" nil))"},
+		{`(string-join '("a" "b" "c") ", ")`, "\"a, b, c\""},
+		{`(string-join '("a" "b"))`, "\"ab\""},
+		{`(string-trim "  a b \n")`, "\"a b\""},
+		{`(string-trim "xxaxx" "x")`, "\"a\""},
+		{`(string-index "héllo" "ll")`, "2"},
+		{`(string-index "hello" "z")`, "nil"},
+		{`(string-replace "a-b-c" "-" "+")`, "\"a+b+c\""},
+		{`(string-prefix-p "ab" "abc")`, "t"},
+		{`(string-suffix-p "ab" "abc")`, "nil"},
+		{`(string= "a" "a")`, "t"},
+		{`(string< "a" "b")`, "t"},
+		{`(string> "a" "b")`, "nil"},
+		{`(number-to-string 1/2)`, "\"1/2\""},
+		{`(number-to-string 1.5)`, "\"1.5\""},
+		{`(string-to-number "42")`, "42"},
+		{`(string-to-number " -1.5 ")`, "-1.5"},
+		{`(string-to-number "2/4")`, "1/2"},
+		{`(string-to-number "123456789012345678901234567890")`, "123456789012345678901234567890"},
+		{`(floatp (string-to-number (number-to-string 2.0)))`, "t"},
+		{`(string-to-number "010")`, "10"},
+		{`(car (member 'b '#1=(a b . #1#)))`, "b"},
+		{`(string-to-number "abc")`, "nil"},
+		{`(string-to-number "NaN")`, "nil"},
+		{`(string-to-number "Inf")`, "nil"},
+		{`(string-to-number "0x1p-2")`, "nil"},
+		{`(string-to-number "1_000")`, "nil"},
+		{`(string-to-number "1 2")`, "nil"},
+		{`(string-to-number "1/0")`, "nil"},
+		{`#\a`, "#\\a"},
+		{`'(#\space #\newline #\x41 #\( #\é)`, "(cons #\\space (cons #\\newline (cons #\\A (cons #\\( (cons #\\é nil)))))"},
+		{`(char "héllo" 1)`, "#\\é"},
+		{`(char-code #\A)`, "65"},
+		{`(code-char 97)`, "#\\a"},
+		{`(code-char 127)`, "#\\x7F"},
+		{`(char-upcase #\é)`, "#\\É"},
+		{`(char-downcase #\A)`, "#\\a"},
+		{`(char-alphabetic-p #\a)`, "t"},
+		{`(char-alphabetic-p #\1)`, "nil"},
+		{`(char-digit-p #\1)`, "t"},
+		{`(string-to-list "hé")`, "(cons #\\h (cons #\\é nil))"},
+		{`(list-to-string (reverse (string-to-list "abc")))`, "\"cba\""},
+		{`(member #\b (string-to-list "abc"))`, "(cons #\\b (cons #\\c nil))"},
+		{`(characterp #\a)`, "t"},
+		{`(characterp "a")`, "nil"},
+		{`(type-of #\a)`, "character"},
+		{`:key`, ":key"},
+		{`(list :a 'b)`, "(cons :a (cons b nil))"},
+		{`(keywordp :a)`, "t"},
+		{`(keywordp 'a)`, "nil"},
+		{`(intern "foo")`, "foo"},
+		{`(member (intern "b") '(a b))`, "(cons b nil)"},
+		{`(intern "nil")`, "nil"},
+		{`(symbol-name 'foo)`, "\"foo\""},
+		{`(symbol-name :foo)`, "\":foo\""},
+		{`(symbol-name nil)`, "\"nil\""},
+		{`(make-symbol "foo")`, "foo"},
+		{`(member (make-symbol "a") '(a))`, "nil"},
+		{"(setq s (make-symbol \"a\"))\n(member s (list 'a s))", "(cons a nil)"},
+		{"(setq g (gensym))\n(list (symbolp g) (member g (list (intern (symbol-name g)))))", "(cons t (cons nil nil))"},
+		{`(string-prefix-p "tmp" (symbol-name (gensym "tmp")))`, "t"},
+		{`(member (gensym) (list (gensym)))`, "nil"},
+		{"(setq x 1)\n(symbol-value 'x)", "1"},
+		{`(symbol-value :a)`, ":a"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(gethash (intern \"a\") h)", "1"},
+		{"(put 'apple 'color 'red)\n(get 'apple 'color)", "red"},
+		{`(get 'pear 'color)`, "nil"},
+		{"(put 'plum 'color 'red)\n(put 'plum 'size 2)\n(put 'plum 'color 'blue)\n(symbol-plist 'plum)", "(cons color (cons blue (cons size (cons 2 nil))))"},
+		{"(put 'fig 'a 1)\n(put 'fig 'b 2)\n(put 'fig 'c 3)\n(list (remprop 'fig 'b) (remprop 'fig 'b) (symbol-plist 'fig))", "(cons t (cons nil (cons (cons a (cons 1 (cons c (cons 3 nil)))) nil)))"},
+		{"(put 'lime 'a 1)\n(remprop 'lime 'a)\n(symbol-plist 'lime)", "nil"},
+		{"(put 'quince 'a 1)\n(setf (car (symbol-plist 'quince)) 'b)\n(get 'quince 'a)", "1"},
+		{"(setq s (make-symbol \"kiwi\"))\n(put s 'a 1)\n(get 'kiwi 'a)", "nil"},
+		{"(setf (get 'grape 'color) 'green)\n(get 'grape 'color)", "green"},
+		{"(setf x 1 y (+ x 1))\n(list x y)", "(cons 1 (cons 2 nil))"},
+		{"(setq x (list 1 2))\n(setf (car x) 3 (cdr (cdr x)) '(4))\nx", "(cons 3 (cons 2 (cons 4 nil)))"},
+		{"(setq v (vector 1 2))\n(setf (aref v 1) 'b)\nv", "#(1 b)"},
+		{"(setq h (make-hash-table 'equal))\n(setf (gethash \"a\" h) 1)\n(gethash \"a\" h)", "1"},
+		{`(list (eq 'a 'a) (eq nil ()) (eq "a" "a") (eq '(1) '(1)))`, "(cons t (cons t (cons nil (cons nil nil))))"},
+		{"(setq x '(1))\n(eq x x)", "t"},
+		{`(list (eql 1 1) (eql 1 1.0) (eql #\a #\a) (eql "a" "a") (eql 1/2 2/4))`, "(cons t (cons nil (cons t (cons nil (cons t nil)))))"},
+		{`(list (equal "a" "a") (equal '(1 (2 "b")) '(1 (2 "b"))) (equal [1 "a"] [1 "a"]) (equal "a" "A") (equal 1 1.0))`, "(cons t (cons t (cons t (cons nil (cons nil nil)))))"},
+		{`(list (equalp "a" "A") (equalp #\a #\A) (equalp 1 1.0) (equalp 1/2 0.5) (equalp '("A" [1]) '("a" [1.0])))`, "(cons t (cons t (cons t (cons t (cons t nil)))))"},
+		{`(list (equalp 1 "1") (equalp #\a "a") (equalp 0.1 1/10) (equalp [1] '(1)))`, "(cons nil (cons nil (cons nil (cons nil nil))))"},
+		{"(setq a (make-hash-table))\n(setq b (make-hash-table))\n(puthash 'x \"A\" a)\n(puthash 'x \"a\" b)\n(list (equal a b) (equalp a b))", "(cons nil (cons t nil))"},
+		{"(setq h (make-hash-table 'equal))\n(puthash [1 \"a\"] 1 h)\n(gethash [1 \"a\"] h)", "1"},
+		{`(handler-case (error "boom") (error (e) e))`, "\"boom\""},
+		{`(handler-case (car 1) (error (e) e))`, "\"car needs a list got: 1\""},
+		{`(handler-case (error 'not-found 'x) (other () 1) (not-found (v) (list v)))`, "(cons x nil)"},
+		{`(handler-case (+ 1 2) (error () 0))`, "3"},
+		{`(handler-case (error 'oops 1) (t () 'caught))`, "caught"},
+		{`(handler-case (error 'oops 1) (condition () 'caught))`, "caught"},
+		{`(handler-case (handler-case (error 'a 1) (b () 2)) (a (v) (+ v 10)))`, "11"},
+		{"(defun safe-div (a b) (handler-case (/ a b) (error () 0)))\n(safe-div 1 0)", "0"},
+		{`(signal 'progress 1)`, "nil"},
+		{`(handler-case (progn (signal 'progress 50) 'done) (progress (p) p))`, "50"},
+		{`(handler-case (progn (signal 'progress 50) 'done) (error (p) p))`, "done"},
+		{`(handler-case (handler-case (signal 'a 1) (b () 2)) (a (v) v))`, "1"},
+		{`(ignore-errors (car 1))`, "nil"},
+		{`(ignore-errors 1 2)`, "2"},
+		{`(handler-case (ignore-errors (signal 'a 1)) (a () 'outer))`, "outer"},
+		{`(unwind-protect 1 2)`, "1"},
+		{"(setq x 0)\n(ignore-errors (unwind-protect (error \"boom\") (setq x 1)))\nx", "1"},
+		{"(setq x 0)\n(list (catch 'done (unwind-protect (throw 'done 1) (setq x 2))) x)", "(cons 1 (cons 2 nil))"},
+		{`(catch 'a (+ 1 (throw 'a 10)))`, "10"},
+		{`(catch 'a 1 2)`, "2"},
+		{`(catch 'a (catch 'b (throw 'a 1)) 2)`, "1"},
+		{`(catch 'a (ignore-errors (throw 'a 1)) 2)`, "1"},
+		{`(restart-case (invoke-restart 'use-default) (use-default () 0))`, "0"},
+		{`(restart-case (+ 1 (invoke-restart 'use-new 5)) (use-new (x) (* x 2)))`, "10"},
+		{`(restart-case 1 (r () 2))`, "1"},
+		{`(restart-case (restart-case (invoke-restart 'outer 1) (inner () 2)) (outer (x) x))`, "1"},
+		{`(restart-case (restart-case (invoke-restart 'r) (r () 'inner)) (r () 'outer))`, "inner"},
+		{"(defun f () (invoke-restart 'skip))\n(restart-case (f) (skip () 'skipped))", "skipped"},
+		{`(restart-case (handler-case (invoke-restart 'r) (t () 'caught)) (r () 'restarted))`, "restarted"},
+		{`(restart-case (ignore-errors (invoke-restart 'r 1)) (r (&optional x) x))`, "1"},
+		{`(+ 1 (call/cc (lambda (k) (+ 10 (k 1)))))`, "2"},
+		{`(call/cc (lambda (k) 1))`, "1"},
+		{`(call/cc (lambda (k) (k)))`, "nil"},
+		{`(call-with-current-continuation (lambda (k) (funcall k 3)))`, "3"},
+		{`(call/cc (lambda (k) (apply k '(4))))`, "4"},
+		{`(funcall call/cc (lambda (k) (k 5)))`, "5"},
+		{`(call/cc (lambda (k) (mapcar (lambda (x) (if (= x 2) (k x) x)) '(1 2 3))))`, "2"},
+		{`(functionp (call/cc (lambda (k) k)))`, "t"},
+		{`(type-of (call/cc (lambda (k) k)))`, "continuation"},
+		{"(setq k nil)\n(+ 1 (call/cc (lambda (c) (setq k c) 1)))\n(k 10)", "11"},
+		{"(let ((k nil) (n 0)) (setq n (+ 1 (call/cc (lambda (c) (setq k c) n)))) (if (< n 3) (k n) n))", "3"},
+		{`(let ((k nil))
+			(mapcar (lambda (x) (call/cc (lambda (c) (setq k c) x))) '(1))
+			(handler-case (k 5) (error (e) e)))`, "\"continuation can not be resumed after the built-in function it was captured in returned\""},
+		{`(let ((k nil))
+			(mapcar (lambda (x) (call/cc (lambda (c) (setq k c) x))) '(1))
+			(list (ignore-errors (k 5)) 'after))`, "(cons nil (cons after nil))"},
+		{`(let ((k nil) (log nil))
+			(mapcar (lambda (x) (call/cc (lambda (c) (setq k c) x))) '(1))
+			(ignore-errors (unwind-protect (k 5) (setq log 'cleaned)))
+			log)`, "cleaned"},
+		{`(let ((k nil) (n 0))
+			(list (+ 1 (call/cc (lambda (c) (setq k c) 1)))
+				(progn (setq n (+ n 1)) (if (< n 3) (k n) n))))`, "(cons 3 (cons 3 nil))"},
+		{`(progn
+			(setq fail-stack nil)
+			(defun fail () (let ((k (car fail-stack))) (setq fail-stack (cdr fail-stack)) (k k)))
+			(defun amb (choices)
+				(let ((c (call/cc (lambda (c) c))))
+					(if (null choices) (fail)
+						(let ((choice (car choices)))
+							(setq choices (cdr choices))
+							(setq fail-stack (cons c fail-stack))
+							choice))))
+			(let* ((a (amb '(1 2 3 4))) (b (amb '(1 2 3 4))))
+				(if (= (+ a b) 7) (list a b) (fail))))`, "(cons 3 (cons 4 nil))"},
+		{`(progn
+			(setq resume nil)
+			(defun walk (tree yield)
+				(cond ((null tree) nil)
+					((consp tree) (walk (car tree) yield) (walk (cdr tree) yield))
+					(t (call/cc (lambda (c) (setq resume c) (yield tree))))))
+			(let ((leaves nil))
+				(let ((leaf (call/cc (lambda (yield) (walk '((1 2) (3)) yield) nil))))
+					(when leaf
+						(setq leaves (cons leaf leaves))
+						(resume nil)))
+				(reverse leaves)))`, "(cons 1 (cons 2 (cons 3 nil)))"},
+		{`(dynamic-wind (lambda () 1) (lambda () 2) (lambda () 3))`, "2"},
+		{"(setq log nil)\n(defun note (x) (lambda () (setq log (cons x log))))\n(dynamic-wind (note 'in) (note 'body) (note 'out))\n(reverse log)",
+			"(cons in (cons body (cons out nil)))"},
+		{`(let ((log nil) (k nil) (n 0))
+			(dynamic-wind
+				(lambda () (setq log (cons 'in log)))
+				(lambda () (call/cc (lambda (c) (setq k c))))
+				(lambda () (setq log (cons 'out log))))
+			(setq n (+ n 1))
+			(if (< n 3) (k nil) (reverse log)))`, "(cons in (cons out (cons in (cons out (cons in (cons out nil))))))"},
+		{`(let ((log nil))
+			(call/cc (lambda (k)
+				(dynamic-wind
+					(lambda () (setq log (cons 'in log)))
+					(lambda () (unwind-protect (k 1) (setq log (cons 'cleanup log))))
+					(lambda () (setq log (cons 'out log))))))
+			(reverse log))`, "(cons in (cons cleanup (cons out nil)))"},
+		{`(let ((log nil))
+			(catch 'done
+				(dynamic-wind
+					(lambda () nil)
+					(lambda () (throw 'done 1))
+					(lambda () (setq log 'out))))
+			log)`, "out"},
+		{`(let ((log nil))
+			(handler-case
+				(dynamic-wind (lambda () nil) (lambda () (error "boom")) (lambda () (setq log 'out)))
+				(error () log)))`, "out"},
+		{`(defvar *depth* 1)`, "*depth*"},
+		{"(defvar *limit* 1)\n(defvar *limit* 2)\n*limit*", "1"},
+		{"(defparameter *size* 1)\n(defparameter *size* 2)\n*size*", "2"},
+		{"(defvar *level* 0 \"Current level\")\n(defun level () *level*)\n(list (let ((*level* 1)) (level)) (level))", "(cons 1 (cons 0 nil))"},
+		{"(defvar *level* 0)\n(defun level () *level*)\n(let* ((*level* 1) (inner (level))) (list inner (level)))", "(cons 1 (cons 1 nil))"},
+		{"(defvar *level* 0)\n(let ((*level* 1)) (let ((*level* 2)) (setq *level* 3) *level*))", "3"},
+		{"(defvar *level* 0)\n(let ((*level* 1)) (setq *level* 2))\n*level*", "0"},
+		{"(defvar *level* 0)\n(setq *level* 5)\n*level*", "5"},
+		{"(defvar *level* 0)\n(catch 'done (let ((*level* 1)) (throw 'done *level*)))\n*level*", "0"},
+		{"(defvar *level* 0)\n(handler-case (let ((*level* 1)) (error \"boom\")) (error () *level*))", "0"},
+		{"(defvar *level* 0)\n(call/cc (lambda (k) (let ((*level* 1)) (k 1))))\n*level*", "0"},
+		{"(defvar *level* 0)\n(let ((*level* 1)) (mapcar (lambda (x) (symbol-value '*level*)) '(a)))", "(cons 1 nil)"},
+		{`(progn
+			(defvar *level* 0)
+			(setq k nil)
+			(setq seen nil)
+			(let ((*level* 1)) (call/cc (lambda (c) (setq k c))) (setq seen (cons *level* seen)))
+			(if (< (length seen) 2) (k nil) (list seen *level*)))`, "(cons (cons 1 (cons 1 nil)) (cons 0 nil))"},
+		{"(let ((*print-circle* t)) *print-circle*)", "t"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(defun f (*x*) (g))\n(list (f 7) *x*)", "(cons 7 (cons 0 nil))"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(list (handler-case (error 'oops 4) (oops (*x*) (g))) *x*)", "(cons 4 (cons 0 nil))"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(defun f (a &optional *x*) (g))\n(list (f 1) (f 1 2))", "(cons nil (cons 2 nil))"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(defun f (&rest *x*) (g))\n(f 1 2)", "(cons 1 (cons 2 nil))"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(funcall (lambda (*x*) (setq *x* 3) (g)) 1)", "3"},
+		{"(defvar *x* 0)\n(defun f (*x*) (lambda () *x*))\n(funcall (f 7))", "0"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(defmacro m (*x*) (list 'quote (g)))\n(m 9)", "9"},
+		{"(defvar *x* 0)\n(defun g () *x*)\n(list (letrec ((*x* 5) (y (g))) (list y (g))) *x*)", "(cons (cons nil (cons 5 nil)) (cons 0 nil))"},
+		{"(defvar *f* nil)\n(defun g () (funcall *f*))\n(labels ((*f* () 'local)) (g))", "local"},
+		{"(defvar *f* nil)\n(defun g () (funcall *f*))\n(list (flet ((*f* () 'local)) (g)) *f*)", "(cons local (cons nil nil))"},
+		{`(let ((log nil))
+			(unwind-protect
+				(dynamic-wind (lambda () nil) (lambda () 1) (lambda () (setq log (cons 'out log))))
+				(setq log (cons 'cleanup log)))
+			(reverse log))`, "(cons out (cons cleanup nil))"},
 	} {
+		e, err := evalString(test.input, newTestScope())
 
-		expr, err := parse(test.input)
 		if err != nil {
 			t.Fatalf("%s", err)
 		}
 
-		s := scope.NewScope(nil)
+		if got := e.String(); got != test.want {
+			t.Errorf("eval `%s` = %s, want %s", test.input, got, test.want)
+
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, test := range []struct {
+		input, want string
+	}{
+		{"(defun add (x y) (+ x y))\n(add 1)", "add needs at least 2 arguments got: 1"},
+		{"(defun add (x y) (+ x y))\n(add 1 2 3)", "add takes at most 2 arguments got: 3"},
+		{`(lambda (1) 1)`, "Parameter has to be a symbol got: 1"},
+		{`(defun "f" (x) x)`, "defun name has to be a symbol got: \"f\""},
+		{`(1 2)`, "Unable to call expression as function: {1}"},
+		{`(+ 1 "2")`, "+ needs numbers got: \"2\""},
+		{`(- "1")`, "- needs numbers got: \"1\""},
+		{`(-)`, "- needs at least one argument"},
+		{`(/ 1 0)`, "Division by zero"},
+		{`(/ 0)`, "Division by zero"},
+		{`(mod 1 0)`, "Division by zero"},
+		{`(mod 1.5 1)`, "mod needs integers got: 1.5"},
+		{`(rem 1 1/2)`, "rem needs integers got: 1/2"},
+		{`(quotient 1)`, "quotient needs two arguments"},
+		{`(< 1 "2")`, "< needs numbers got: \"2\""},
+		{`(= "1")`, "= needs numbers got: \"1\""},
+		{`(max)`, "max needs at least one argument"},
+		{`(evenp 1.0)`, "evenp needs integers got: 1.0"},
+		{`(1+ nil)`, "1+ needs numbers got: nil"},
+		{`(null)`, "null needs one argument"},
+		{`(let ((t 1)) t)`, "Invalid let binding: (cons t (cons 1 nil))"},
+		{`(expt 2 1/2)`, "expt needs an integer power got: 1/2"},
+		{`(expt 0 (- 1))`, "Division by zero"},
+		{`(expt 2 -9223372036854775808)`, "expt power is out of range got: -9223372036854775808"},
+		{`(if t)`, "if needs two or three arguments"},
+		{`(cond 1)`, "cond clause has to be a list got: 1"},
+		{`(when)`, "when needs a test"},
+		{`(if undefined 1 2)`, "Symbol not found in scope: {undefined}"},
+		{`(let 1)`, "let needs a list of bindings got: 1"},
+		{`(let ((1 2)) 1)`, "Invalid let binding: (cons 1 (cons 2 nil))"},
+		{`(let* ((x 1 2)) 1)`, "Invalid let* binding: (cons x (cons 1 (cons 2 nil)))"},
+		{`(flet ((f)) 1)`, "Invalid flet function: (cons f nil)"},
+		{"(flet ((f (n) (if n (f nil) 0))) (f 1))", "Symbol not found in scope: {f}"},
+		{"(let ((x 1)) x)\nx", "Symbol not found in scope: {x}"},
+		{`,x`, "unquote outside of quasiquote"},
+		{"`,@x", "unquote-splicing has to be inside a list"},
+		{"`(1 ,@2)", "unquote-splicing needs a list got: 2"},
+		{`(quote 1 2)`, "quote needs one argument"},
+		{`'`, "Parsing error: quote needs an expression"},
+		{"(defmacro add (a b) `(+ ,b ,a))\n(add 1)", "add needs at least 2 arguments got: 1"},
+		{`(defmacro 1 (a) a)`, "defmacro name has to be a symbol got: 1"},
+		{"(define-syntax one (syntax-rules () ((_ a) a)))\n(one)", "No syntax-rules pattern matches: nil"},
+		{"(define-syntax bad (syntax-rules () ((_ a ...) a)))\n(bad 1)", "Pattern variable used without an ellipsis: a"},
+		{"(define-syntax bad (syntax-rules () ((_ a) (a ...))))\n(bad 1)", "Ellipsis without pattern variables: a"},
+		{`(cons 1)`, "cons needs two arguments"},
+		{`(car 1)`, "car needs a list got: 1"},
+		{`(cadr (cons 1 2))`, "cadr needs a list got: 2"},
+		{`(nth (- 1) '(1))`, "nth needs a non-negative integer got: -1"},
+		{`(nthcdr 1.5 '(1))`, "nthcdr needs a non-negative integer got: 1.5"},
+		{`(length 1)`, "length needs a proper list got: 1"},
+		{`(append 1 '(2))`, "append needs a proper list got: 1"},
+		{`(reverse "abc")`, "reverse needs a proper list got: \"abc\""},
+		{`(assoc 1 '(1 2))`, "assoc needs a list of pairs got: 1"},
+		{`(last 1)`, "last needs a list got: 1"},
+		{`(length '#1=(a . #1#))`, "length needs a proper list got: #1=(cons a #1#)"},
+		{`(length '#1=(a b c . #1#))`, "length needs a proper list got: #1=(cons a (cons b (cons c #1#)))"},
+		{`(length '(a . #1=(b c . #1#)))`, "length needs a proper list got: (cons a #1=(cons b (cons c #1#)))"},
+		{`(member 'z '#1=(a b . #1#))`, "member needs a proper list got: #1=(cons a (cons b #1#))"},
+		{`(mapcar 'print '#1=(a . #1#))`, "mapcar needs a proper list got: #1=(cons a #1#)"},
+		{`(append '#1=(a . #1#) '(b))`, "append needs a proper list got: #1=(cons a #1#)"},
+		{`(last '#1=(a b . #1#))`, "last needs a proper list got: #1=(cons a (cons b #1#))"},
+		{`(copy-list '#1=(a . #1#))`, "copy-list needs a proper list got: #1=(cons a #1#)"},
+		{`(reverse '#1=(a . #1#))`, "reverse needs a proper list got: #1=(cons a #1#)"},
+		{`(list*)`, "list* needs at least one argument"},
+		{`(mapcar '1+)`, "mapcar needs a function and a list"},
+		{`(mapcar '1+ 1)`, "mapcar needs a proper list got: 1"},
+		{`(mapcar 1 '(1))`, "Unable to call expression as function: {1}"},
+		{`(funcall 'undefined)`, "Symbol not found in scope: {undefined}"},
+		{`(apply '+ 1 2)`, "apply needs a proper list got: 2"},
+		{"(defun add (x y) (+ x y))\n(funcall 'add 1)", "add needs at least 2 arguments got: 1"},
+		{`(reduce '+)`, "reduce needs a function, a list and an optional initial value"},
+		{`(filter 'oddp '(1 "a"))`, "oddp needs numbers got: \"a\""},
+		{`(setcar nil 1)`, "setcar needs a cons got: nil"},
+		{`(set-cdr! '(1))`, "set-cdr! needs two arguments"},
+		{`(length '(1 . 2))`, "length needs a proper list got: (cons 1 2)"},
+		{`(1 { 2)`, "Parsing error: invalid token {"},
+		{`(make-hash-table 'foo)`, "make-hash-table needs eq, eql or equal as test got: foo"},
+		{`(gethash 'a 1)`, "gethash needs a hash table got: 1"},
+		{`(puthash 'a 1)`, "puthash needs a key, a value and a hash table"},
+		{"(setq h (make-hash-table))\n(puthash 'a 1 h)\n(maphash 'car h)", "car needs one argument"},
+		{`(aref [1 2] 2)`, "aref index out of range got: 2"},
+		{`(aref '(1) 0)`, "aref needs a vector got: (cons 1 nil)"},
+		{`(aset [1] (- 1) 0)`, "aset needs a non-negative integer got: -1"},
+		{`(make-vector 'a)`, "make-vector needs a non-negative integer got: a"},
+		{`(make-vector 9223372036854775807)`, "make-vector length is too large got: 9223372036854775807"},
+		{`(subseq [1 2 3] 2 1)`, "subseq end is before start got: 1"},
+		{`(subseq [1 2 3] 4)`, "subseq index out of range got: 4"},
+		{`(subseq 1 0)`, "subseq needs a vector or a list got: 1"},
+		{`(list-to-vector 1)`, "list-to-vector needs a proper list got: 1"},
+		{`[1 2)`, "Parsing error: unexpected )"},
+		{`(1 2]`, "Parsing error: unexpected ]"},
+		{`[1 . 2]`, "Parsing error: vector can not have a dot"},
+		{`(concat "a" 1)`, "concat needs a string got: 1"},
+		{`(string-length 'a)`, "string-length needs a string got: a"},
+		{`(substring "abc" 4)`, "substring index out of range got: 4"},
+		{`(substring "abc" 2 1)`, "substring end is before start got: 1"},
+		{`(string-join '("a" 1))`, "string-join needs a string got: 1"},
+		{`(string= "a")`, "string= needs two arguments"},
+		{`(number-to-string "1")`, "number-to-string needs a number got: \"1\""},
+		{`(char-code "a")`, "char-code needs a character got: \"a\""},
+		{`(char "abc" 3)`, "char index out of range got: 3"},
+		{`(code-char 1114112)`, "code-char needs a character code got: 1114112"},
+		{`(list-to-string '(#\a 1))`, "list-to-string needs a character got: 1"},
+		{`#\foo`, "Parsing error: invalid character #\\foo"},
+		{`(setq :a 1)`, "setq needs a symbol got: :a"},
+		{`(symbol-name "a")`, "symbol-name needs a symbol got: \"a\""},
+		{`(symbol-value 'undefined)`, "Symbol not found in scope: {undefined}"},
+		{`(intern 'a)`, "intern needs a string got: a"},
+		{`(invoke-restart 'nope)`, "invoke-restart has no restart named: nope"},
+		{"(restart-case 1 (r () 2))\n(invoke-restart 'r)", "invoke-restart has no restart named: r"},
+		{`(invoke-restart)`, "invoke-restart needs a restart name"},
+		{`(restart-case 1 (r))`, "restart-case clause needs a name and a parameter list got: (cons r nil)"},
+		{`(restart-case 1 ("r" ()))`, "restart-case needs a symbol as restart name got: \"r\""},
+		{`(restart-case (invoke-restart 'r 1 2) (r (x) x))`, "r takes at most 1 arguments got: 2"},
+		{`(error "boom")`, "boom"},
+		{`(error 'not-found 'x)`, "not-found: x"},
+		{`(error 'not-found "key")`, "not-found: key"},
+		{`(handler-case (error 'a 1) (b () 2))`, "a: 1"},
+		{`(handler-case (car 1) (not-found () 2))`, "car needs a list got: 1"},
+		{`(throw 'x 1)`, "throw with no catch for tag: x"},
+		{`(handler-case (throw 'x 1) (t () 1))`, "throw with no catch for tag: x"},
+		{`(catch 'a (throw 'b 1))`, "throw with no catch for tag: b"},
+		{`(unwind-protect 1 (error "cleanup"))`, "cleanup"},
+		{`(error)`, "error needs a value or a condition type and a value"},
+		{`(error "a" 1)`, "error needs a symbol as condition type got: \"a\""},
+		{`(handler-case 1 x)`, "handler-case clause needs a type and a parameter list got: x"},
+		{`(handler-case 1 (error (a b)))`, "handler-case clause needs at most one parameter got: (cons a (cons b nil))"},
+		{`(handler-case 1 ("e" ()))`, "handler-case needs a symbol as condition type got: \"e\""},
+		{`(eq 1)`, "eq needs two arguments"},
+		{`(equalp 1 2 3)`, "equalp needs two arguments"},
+		{`(get "apple" 'color)`, "get needs a symbol got: \"apple\""},
+		{`(put nil 'color 'red)`, "put needs a symbol got: nil"},
+		{`(setf (car x))`, "setf needs pairs of places and values"},
+		{`(setf (length x) 1)`, "setf does not know how to set: (cons length (cons x nil))"},
+		{`(setf 1 1)`, "setf needs a place got: 1"},
+		{`'#1#`, "Parsing error: undefined label #1#"},
+		{`'#1=#1#`, "Parsing error: label #1= refers to itself"},
+		{`'(#1=)`, "Parsing error: label #1= needs an expression"},
+		{`'#a`, "Parsing error: invalid token #"},
+		{`'(. 1)`, "Parsing error: dotted list needs an expression before the dot"},
+		{`'(1 .)`, "Parsing error: dotted list needs an expression after the dot"},
+		{`'(1 . 2 3)`, "Parsing error: dotted list needs one expression after the dot"},
+		{`(define-syntax bad 1)`, "define-syntax needs a macro got: 1"},
+		{"(define-syntax local (syntax-rules () ((_ e) (let ((x 1)) e))))\n(local x)", "Symbol not found in scope: {x}"},
+		{`(call/cc)`, "call/cc needs one argument"},
+		{`(setq false 1)`, "setq needs a symbol got: nil"},
+		{`(setq true nil)`, "setq needs a symbol got: t"},
+		{`(let ((false 1)) false)`, "Invalid let binding: (cons nil (cons 1 nil))"},
+		{`(defvar)`, "defvar needs a name, an optional value and an optional documentation"},
+		{`(defparameter *x*)`, "defparameter needs a name, a value and an optional documentation"},
+		{`(defvar "x" 1)`, "defvar name has to be a symbol got: \"x\""},
+		{`(defparameter :x 1)`, "defparameter name has to be a symbol got: :x"},
+		{`(defvar *x* 1 2)`, "defvar documentation has to be a string got: 2"},
+		{"(defvar *unset*)\n*unset*", "Symbol not found in scope: {*unset*}"},
+		{`(call/cc (lambda (k) (k 1 2)))`, "continuation takes at most 1 arguments got: 2"},
+		{`(dynamic-wind (lambda () 1) (lambda () 2))`, "dynamic-wind needs three functions"},
+		{`(let ((k nil) (n 0))
+			(mapcar (lambda (x) (call/cc (lambda (c) (setq k c) x))) '(1 2))
+			(setq n (+ n 1))
+			(if (< n 2) (k 5) n))`, "continuation can not be resumed after the built-in function it was captured in returned"},
+	} {
+		_, err := evalString(test.input, newTestScope())
+
+		if err == nil {
+			t.Fatalf("eval `%s` expected error %s", test.input, test.want)
+		}
+
+		if got := err.Error(); got != test.want {
+			t.Errorf("eval `%s` = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestConditionError(t *testing.T) {
+	_, err := evalString(`(error 'not-found '(a 1))`, newTestScope())
+
+	c, ok := err.(*condition)
+
+	if !ok {
+		t.Fatalf("got: %v want a condition", err)
+	}
+
+	if c.typ != syntax.Intern("not-found") || c.signaled {
+		t.Errorf("got: %s signaled %t want not-found", c.typ.Name, c.signaled)
+	}
+
+	if got := c.value.String(); got != "(cons a (cons 1 nil))" {
+		t.Errorf("got: %s want (cons a (cons 1 nil))", got)
+	}
+}
+
+func TestNestedEval(t *testing.T) {
+	s := newTestScope()
+
+	// eval-in-go evaluates its argument in a new run started by Go code
+	// while the run calling it waits.
+	s.Set(syntax.Intern("eval-in-go"), &scope.FuncExpr{
+		Name: "eval-in-go",
+		Fn: func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+			return eval(args[0], s)
+		},
+	})
+
+	for _, test := range []struct {
+		input, want string
+	}{
+		{`(handler-case (eval-in-go '(error "boom")) (error (e) e))`, "\"boom\""},
+		{`(catch 'done (eval-in-go '(throw 'done 1)) 2)`, "1"},
+		{`(+ 1 (call/cc (lambda (k) (eval-in-go '(k 2)))))`, "3"},
+		{`(list (eval-in-go '(handler-case (car 1) (error () 'inner))) 'outer)`, "(cons inner (cons outer nil))"},
+	} {
+		e, err := evalString(test.input, s)
+
+		if err != nil {
+			t.Fatalf("eval `%s`: %s", test.input, err)
+		}
+
+		if got := e.String(); got != test.want {
+			t.Errorf("eval `%s` = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSpecialPerScope(t *testing.T) {
+	if _, err := evalString("(defvar *scoped* 0)", newTestScope()); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// *scoped* is only special in the scope which declared it.
+	input := "(setq *scoped* 1)\n(defun scoped () *scoped*)\n(let ((*scoped* 2)) (scoped))"
+	e, err := evalString(input, newTestScope())
+
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if got := e.String(); got != "1" {
+		t.Errorf("eval `%s` = %s, want 1", input, got)
+	}
+}
+
+func TestConcurrentEval(t *testing.T) {
+	// every test is evaluated at the same time in separate global scopes.
+	for _, test := range []struct {
+		input, want string
+	}{
+		{"(handler-case (mapcar (lambda (x) (if (= x 3) (error 'bad x) (call/cc (lambda (k) (k x))))) '(1 2 3)) (bad (v) (list 'caught v)))", "(cons caught (cons 3 nil))"},
+		{"(define-syntax swap (syntax-rules () ((_ a b) (let ((tmp a)) (setq a b) (setq b tmp)))))\n(let ((x 1) (y 2)) (swap x y) (list x y))", "(cons 2 (cons 1 nil))"},
+		{"(defvar *level* 0)\n(defun level () *level*)\n(let ((*level* 1)) (level))", "1"},
+		{"(put 'shared 'count 1)\n(put 'shared 'count (+ (get 'shared 'count) 1))\n(remprop 'shared 'other)\n(length (symbol-plist 'shared))", "2"},
+	} {
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
 
-		b := newBuiltins()
-		for k, v := range b.fn {
-			s.Set(k, v)
+			go func() {
+				defer wg.Done()
+				s := newTestScope()
+
+				for j := 0; j < 200; j++ {
+					e, err := evalString(test.input, s)
+
+					if err != nil {
+						t.Errorf("eval `%s`: %s", test.input, err)
+						return
+					}
+
+					if got := e.String(); got != test.want {
+						t.Errorf("eval `%s` = %s, want %s", test.input, got, test.want)
+						return
+					}
+				}
+			}()
 		}
 
-		e, err := eval(expr[0], s)
+		wg.Wait()
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// without tail calls these loops would need far more stack
+	// than allowed here.
+	defer debug.SetMaxStack(debug.SetMaxStack(4 << 20))
+
+	for _, test := range []struct {
+		input, want string
+	}{
+		{"(defun loop (n) (if n (loop (dec n)) 'done))\n(loop 100000)", "done"},
+		{"(defun loop (n) (cond ((not n) 'done) (t (loop (dec n)))))\n(loop 100000)", "done"},
+		{"(defun loop (n) (when n (loop (dec n))))\n(loop 100000)", "nil"},
+		{"(defun loop (n) (unless (not n) (loop (dec n))))\n(loop 100000)", "nil"},
+		{"(defun loop (n) (or (not n) (loop (dec n))))\n(loop 100000)", "t"},
+		{"(defun loop (n) (and n (loop (dec n))))\n(loop 100000)", "nil"},
+		{"(defun loop (n) (let ((m (dec n))) (if m (loop m) 'done)))\n(loop 100000)", "done"},
+		{"(defun loop (n) (let* ((m (dec n))) (progn 1 (if m (loop m) 'done))))\n(loop 100000)", "done"},
+		{"(defun even (n) (if n (odd (dec n)) t))\n(defun odd (n) (if n (even (dec n)) nil))\n(even 100000)", "t"},
+		{"(labels ((loop (n) (if n (loop (dec n)) 'done))) (loop 100000))", "done"},
+		{"(defmacro again (n) `(loop (dec ,n)))\n(defun loop (n) (if n (again n) 'done))\n(loop 100000)", "done"},
+		{"(defun count (n) (if n (+ 1 (count (dec n))) 0))\n(count 100000)", "100000"},
+		{"(defvar *step* 1)\n(defun count (n) (if n (+ *step* (count (dec n))) 0))\n(count 40000)", "40000"},
+		{"(defvar *depth* 0)\n(defun deep (n) (if n (let ((*depth* (+ *depth* 1))) (deep (dec n))) *depth*))\n(deep 40000)", "40000"},
+	} {
+		s := newTestScope()
+
+		// dec returns n-1 or nil once it reaches 0.
+		s.Set(syntax.Intern("dec"), &scope.FuncExpr{
+			Name: "dec",
+			Fn: func(_ *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+				n := args[0].(*syntax.AtomExpr).Value.(int64) - 1
+
+				if n == 0 {
+					return &syntax.NilExpr{}, nil
+				}
+				return &syntax.AtomExpr{Token: syntax.INT, Raw: fmt.Sprint(n), Value: n}, nil
+			},
+		})
+
+		e, err := evalString(test.input, s)
 
 		if err != nil {
 			t.Fatalf("%s", err)
@@ -40,7 +946,6 @@ func TestEval(t *testing.T) {
 
 		if got := e.String(); got != test.want {
 			t.Errorf("eval `%s` = %s, want %s", test.input, got, test.want)
-
 		}
 	}
 }
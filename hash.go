@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// hashTests holds the names of the hash table tests. eq compares keys
+// like eql, which only differs for numbers and characters.
+var hashTests = map[string]bool{
+	"eq":    true,
+	"eql":   true,
+	"equal": true,
+}
+
+// hashTableArg returns a s-expression as a hash table.
+func hashTableArg(name string, e syntax.Sexpr) (*syntax.HashTableExpr, error) {
+	h, ok := e.(*syntax.HashTableExpr)
+
+	if !ok {
+		return nil, fmt.Errorf("%s needs a hash table got: %s", name, e)
+	}
+
+	return h, nil
+}
+
+// builtinMakeHashTable creates an empty hash table comparing keys with eql
+// or with the test named by its optional argument.
+// (make-hash-table 'equal)
+func builtinMakeHashTable(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("make-hash-table takes at most one argument")
+	}
+
+	if len(args) == 0 {
+		return syntax.NewHashTable("eql"), nil
+	}
+
+	if symbol, ok := args[0].(*syntax.SymbolExpr); ok {
+		if hashTests[symbol.Name] {
+			return syntax.NewHashTable(symbol.Name), nil
+		}
+	}
+
+	return nil, fmt.Errorf("make-hash-table needs eq, eql or equal as test got: %s", args[0])
+}
+
+// builtinGethash returns the value of a key in a hash table or the
+// optional default value when the key is not found.
+// (gethash 'a table 0)
+func builtinGethash(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("gethash needs a key, a hash table and an optional default")
+	}
+
+	h, err := hashTableArg("gethash", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := h.Get(args[0]); ok {
+		return value, nil
+	}
+
+	if len(args) == 3 {
+		return args[2], nil
+	}
+
+	return &syntax.NilExpr{}, nil
+}
+
+// builtinPuthash sets the value of a key in a hash table and returns the
+// value.
+// (puthash 'a 1 table)
+func builtinPuthash(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("puthash needs a key, a value and a hash table")
+	}
+
+	h, err := hashTableArg("puthash", args[2])
+
+	if err != nil {
+		return nil, err
+	}
+
+	h.Put(args[0], args[1])
+	return args[1], nil
+}
+
+// builtinRemhash deletes a key from a hash table and returns whether the
+// key was found.
+// (remhash 'a table)
+func builtinRemhash(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("remhash needs a key and a hash table")
+	}
+
+	h, err := hashTableArg("remhash", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return boolExpr(h.Remove(args[0])), nil
+}
+
+// builtinClrhash deletes every key from a hash table and returns it.
+// (clrhash table)
+func builtinClrhash(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("clrhash needs one argument")
+	}
+
+	h, err := hashTableArg("clrhash", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	h.Clear()
+	return h, nil
+}
+
+// builtinHashTableCount returns the number of keys in a hash table.
+// (hash-table-count table)
+func builtinHashTableCount(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("hash-table-count needs one argument")
+	}
+
+	h, err := hashTableArg("hash-table-count", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newNumber(int64(h.Count())), nil
+}
+
+// builtinHashTableKeys returns a list with the keys of a hash table in
+// the order they were added.
+// (hash-table-keys table)
+func builtinHashTableKeys(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("hash-table-keys needs one argument")
+	}
+
+	h, err := hashTableArg("hash-table-keys", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]syntax.Sexpr, 0, h.Count())
+
+	h.Each(func(key, _ syntax.Sexpr) error {
+		keys = append(keys, key)
+		return nil
+	})
+
+	return sliceToList(keys), nil
+}
+
+// builtinMaphash calls a function with every key and value of a hash
+// table and returns nil.
+// (maphash (lambda (k v) (print k)) table)
+func builtinMaphash(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("maphash needs a function and a hash table")
+	}
+
+	h, err := hashTableArg("maphash", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Each(func(key, value syntax.Sexpr) error {
+		_, err := apply(s, args[0], []syntax.Sexpr{key, value})
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &syntax.NilExpr{}, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// newLambda returns a user-defined function which captures the scope s.
+// Every call binds the arguments in a new child scope of s before
+// evaluating the body.
+func newLambda(name string, s *scope.Scope, params syntax.Sexpr, body []syntax.Sexpr) (*scope.FuncExpr, error) {
+	l, err := parseParams(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l.Body = body
+	l.Scope = s
+
+	f := &scope.FuncExpr{Name: name, Lambda: l}
+	f.Fn = func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		return callFunction(s, f, args)
+	}
+
+	return f, nil
+}
+
+// parseParams parses a lambda list into a lambda without a body.
+// &body is the same as &rest.
+// (x y &optional z &rest r)
+func parseParams(params syntax.Sexpr) (*scope.Lambda, error) {
+	l := &scope.Lambda{}
+
+	list, err := listToSlice(params)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid parameter list: %s", params)
+	}
+
+	optional := false
+	for i := 0; i < len(list); i++ {
+		symbol, ok := list[i].(*syntax.SymbolExpr)
+
+		if !ok {
+			return nil, fmt.Errorf("Parameter has to be a symbol got: %s", list[i])
+		}
+
+		switch symbol.Name {
+		case "&optional":
+			optional = true
+		case "&rest", "&body":
+			if i != len(list)-2 {
+				return nil, fmt.Errorf("&rest needs exactly one parameter")
+			}
+
+			rest, ok := list[i+1].(*syntax.SymbolExpr)
+
+			if !ok {
+				return nil, fmt.Errorf("Parameter has to be a symbol got: %s", list[i+1])
+			}
+
+			l.Rest = rest
+			i++
+		default:
+			if optional {
+				l.Optional = append(l.Optional, symbol)
+			} else {
+				l.Params = append(l.Params, symbol)
+			}
+		}
+	}
+
+	return l, nil
+}
+
+// bindParams creates the scope for a function call with every parameter
+// bound to its argument and returns it with the continuation of the
+// body, where special variables among the parameters are bound. Missing
+// optional parameters are bound to nil.
+func bindParams(name string, l *scope.Lambda, args []syntax.Sexpr, k *continuation) (*scope.Scope, *continuation, error) {
+	if len(args) < len(l.Params) {
+		return nil, nil, fmt.Errorf("%s needs at least %d arguments got: %d", name, len(l.Params), len(args))
+	}
+
+	if l.Rest == nil && len(args) > len(l.Params)+len(l.Optional) {
+		return nil, nil, fmt.Errorf("%s takes at most %d arguments got: %d", name, len(l.Params)+len(l.Optional), len(args))
+	}
+
+	s := scope.NewScope(l.Scope)
+
+	for i, p := range l.Params {
+		k = bindVariable(s, p, args[i], k)
+	}
+
+	args = args[len(l.Params):]
+	for _, p := range l.Optional {
+		if len(args) == 0 {
+			k = bindVariable(s, p, &syntax.NilExpr{}, k)
+			continue
+		}
+
+		k = bindVariable(s, p, args[0], k)
+		args = args[1:]
+	}
+
+	if l.Rest != nil {
+		k = bindVariable(s, l.Rest, sliceToList(args), k)
+	}
+
+	return s, k, nil
+}
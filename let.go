@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// A binding is a symbol with the s-expression used to initialize it.
+type binding struct {
+	symbol *syntax.SymbolExpr
+	init   syntax.Sexpr
+}
+
+// parseBindings parses the binding list of a let form. A binding can be
+// a symbol, which is bound to nil, or a list with a symbol and a value.
+// ((x 1) (y 2) z)
+func parseBindings(name string, e syntax.Sexpr) ([]binding, error) {
+	list, err := listToSlice(e)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s needs a list of bindings got: %s", name, e)
+	}
+
+	bindings := make([]binding, 0, len(list))
+
+	for _, b := range list {
+		if symbol, ok := b.(*syntax.SymbolExpr); ok {
+			bindings = append(bindings, binding{symbol, &syntax.NilExpr{}})
+			continue
+		}
+
+		pair, err := listToSlice(b)
+
+		if err != nil || len(pair) < 1 || len(pair) > 2 {
+			return nil, fmt.Errorf("Invalid %s binding: %s", name, b)
+		}
+
+		symbol, ok := pair[0].(*syntax.SymbolExpr)
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid %s binding: %s", name, b)
+		}
+
+		var init syntax.Sexpr = &syntax.NilExpr{}
+		if len(pair) == 2 {
+			init = pair[1]
+		}
+
+		bindings = append(bindings, binding{symbol, init})
+	}
+
+	return bindings, nil
+}
+
+// formLet evaluates all values in the current scope and then binds them
+// in a new scope for the body. Special variables are bound dynamically
+// until the body is left.
+// (let ((x 1) (y 2)) (+ x y))
+func formLet(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("let needs a list of bindings")
+	}
+
+	bindings, err := parseBindings("let", ss[0])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	return evalArgs(s, inits(bindings), nil, k, func(values []syntax.Sexpr, k *continuation) (state, error) {
+		local := scope.NewScope(s)
+		bound := k
+
+		for i, b := range bindings {
+			bound = bindVariable(local, b.symbol, values[i], bound)
+		}
+
+		return enterBindings(k, bound, func(k *continuation) (state, error) {
+			return evalSequence(ss[1:], local, k)
+		})
+	})
+}
+
+// inits returns the s-expressions initializing bindings.
+func inits(bindings []binding) []syntax.Sexpr {
+	ss := make([]syntax.Sexpr, len(bindings))
+
+	for i, b := range bindings {
+		ss[i] = b.init
+	}
+
+	return ss
+}
+
+// formLetStar binds each value in order so a value can refer to the
+// bindings before it.
+// (let* ((x 1) (y (+ x 1))) y)
+func formLetStar(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("let* needs a list of bindings")
+	}
+
+	bindings, err := parseBindings("let*", ss[0])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	return bindSequentially(s, bindings, ss[1:], k)
+}
+
+// bindSequentially binds the first binding in a new scope where the
+// next ones are bound and evaluates the body once all are bound.
+func bindSequentially(s *scope.Scope, bindings []binding, body []syntax.Sexpr, k *continuation) (state, error) {
+	if len(bindings) == 0 {
+		return evalSequence(body, scope.NewScope(s), k)
+	}
+
+	b := bindings[0]
+
+	return evaluate(b.init, s, k.then(func(value syntax.Sexpr, k *continuation) (state, error) {
+		local := scope.NewScope(s)
+
+		return enterBindings(k, bindVariable(local, b.symbol, value, k), func(k *continuation) (state, error) {
+			return bindSequentially(local, bindings[1:], body, k)
+		})
+	}))
+}
+
+// formLetrec binds all symbols to nil in a new scope and then evaluates
+// each value inside of it, which lets functions refer to each other.
+// (letrec ((even (lambda (n) ...)) (odd (lambda (n) ...))) (even 4))
+func formLetrec(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("letrec needs a list of bindings")
+	}
+
+	bindings, err := parseBindings("letrec", ss[0])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	local := scope.NewScope(s)
+	bound := k
+
+	for _, b := range bindings {
+		bound = bindVariable(local, b.symbol, &syntax.NilExpr{}, bound)
+	}
+
+	return enterBindings(k, bound, func(k *continuation) (state, error) {
+		return evalArgs(local, inits(bindings), nil, k, func(values []syntax.Sexpr, k *continuation) (state, error) {
+			for i, b := range bindings {
+				setSymbol(local, b.symbol, values[i])
+			}
+
+			return evalSequence(ss[1:], local, k)
+		})
+	})
+}
+
+// formFlet defines local functions. The functions capture the current
+// scope so they can not call themselves.
+// (flet ((double (x) (+ x x))) (double 2))
+func formFlet(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return localFunctions("flet", s, scope.NewScope(s), ss, k)
+}
+
+// formLabels defines local functions which capture the new scope so they
+// can call themselves and each other.
+// (labels ((even (n) ...) (odd (n) ...)) (even 4))
+func formLabels(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	local := scope.NewScope(s)
+	return localFunctions("labels", local, local, ss, k)
+}
+
+// localFunctions creates every function definition in ss[0] capturing
+// the scope captured, binds them in local and evaluates the body there.
+func localFunctions(name string, captured, local *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("%s needs a list of functions", name)
+	}
+
+	definitions, err := listToSlice(ss[0])
+
+	if err != nil {
+		return state{}, fmt.Errorf("%s needs a list of functions got: %s", name, ss[0])
+	}
+
+	bound := k
+
+	for _, d := range definitions {
+		definition, err := listToSlice(d)
+
+		if err != nil || len(definition) < 2 {
+			return state{}, fmt.Errorf("Invalid %s function: %s", name, d)
+		}
+
+		symbol, ok := definition[0].(*syntax.SymbolExpr)
+
+		if !ok {
+			return state{}, fmt.Errorf("Invalid %s function: %s", name, d)
+		}
+
+		f, err := newLambda(symbol.Name, captured, definition[1], definition[2:])
+
+		if err != nil {
+			return state{}, err
+		}
+
+		bound = bindVariable(local, symbol, f, bound)
+	}
+
+	return enterBindings(k, bound, func(k *continuation) (state, error) {
+		return evalSequence(ss[1:], local, k)
+	})
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// properList returns the elements of a list or an error naming the
+// function when the list does not end with nil.
+func properList(name string, e syntax.Sexpr) ([]syntax.Sexpr, error) {
+	list, err := listToSlice(e)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s needs a proper list got: %s", name, e)
+	}
+
+	return list, nil
+}
+
+// indexArg returns a s-expression as a non-negative index.
+func indexArg(name string, e syntax.Sexpr) (int, error) {
+	v, kind, ok := numberValue(e)
+
+	if !ok || kind != intKind || v.(int64) < 0 {
+		return 0, fmt.Errorf("%s needs a non-negative integer got: %s", name, e)
+	}
+
+	return int(v.(int64)), nil
+}
+
+// car returns the first value of a cons. The car of nil is nil.
+func car(name string, e syntax.Sexpr) (syntax.Sexpr, error) {
+	switch e := e.(type) {
+	case *syntax.ConsExpr:
+		return e.Car, nil
+	case *syntax.NilExpr:
+		return e, nil
+	}
+	return nil, fmt.Errorf("%s needs a list got: %s", name, e)
+}
+
+// cdr returns the rest of a cons. The cdr of nil is nil.
+func cdr(name string, e syntax.Sexpr) (syntax.Sexpr, error) {
+	switch e := e.(type) {
+	case *syntax.ConsExpr:
+		return e.Cdr, nil
+	case *syntax.NilExpr:
+		return e, nil
+	}
+	return nil, fmt.Errorf("%s needs a list got: %s", name, e)
+}
+
+// setter returns a built-in function which destructively replaces the car
+// or the cdr of a cons and returns the new value.
+// (setcar x 1)
+func setter(name string, isCar bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s needs two arguments", name)
+		}
+
+		cons, ok := args[0].(*syntax.ConsExpr)
+
+		if !ok {
+			return nil, fmt.Errorf("%s needs a cons got: %s", name, args[0])
+		}
+
+		if isCar {
+			cons.Car = args[1]
+		} else {
+			cons.Cdr = args[1]
+		}
+
+		return args[1], nil
+	}
+}
+
+// builtinList creates a list by linking a set of const together.
+// (list 4 5 6) => (cons 4 (cons 5 (cons 6 nil)))
+func builtinList(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	return sliceToList(args), nil
+}
+
+// builtinListStar creates a list whose tail is the last argument.
+// (list* 1 2 '(3 4)) => (cons 1 (cons 2 (cons 3 (cons 4 nil))))
+func builtinListStar(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("list* needs at least one argument")
+	}
+
+	expr := args[len(args)-1]
+
+	for i := len(args) - 2; i >= 0; i-- {
+		expr = &syntax.ConsExpr{Car: args[i], Cdr: expr}
+	}
+
+	return expr, nil
+}
+
+// builtinCons creates a new cons.
+// (cons 1 '(2 3))
+func builtinCons(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("cons needs two arguments")
+	}
+
+	return &syntax.ConsExpr{Car: args[0], Cdr: args[1]}, nil
+}
+
+// builtinCar returns the first value of a list (const.car).
+// (car '(1 2 3)) => 1
+func builtinCar(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("car needs one argument")
+	}
+
+	return car("car", args[0])
+}
+
+// builtinCdr returns a list without its first value (const.cdr).
+// (cdr '(1 2 3)) => (2 3)
+func builtinCdr(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("cdr needs one argument")
+	}
+
+	return cdr("cdr", args[0])
+}
+
+// cxr returns a built-in function composing car and cdr. The path is
+// read from right to left so "ad" returns the car of the cdr.
+// (cadr '(1 2 3)) => 2
+func cxr(name, path string) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		e := args[0]
+
+		for i := len(path) - 1; i >= 0; i-- {
+			var err error
+
+			if path[i] == 'a' {
+				e, err = car(name, e)
+			} else {
+				e, err = cdr(name, e)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return e, nil
+	}
+}
+
+// nthcdr returns the list after calling cdr n times. It stops at the
+// end of the list since the cdr of nil is nil.
+func nthcdr(name string, n int, e syntax.Sexpr) (syntax.Sexpr, error) {
+	for ; n > 0; n-- {
+		var err error
+
+		if _, ok := e.(*syntax.NilExpr); ok {
+			return e, nil
+		}
+
+		if e, err = cdr(name, e); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// builtinNthcdr returns the list after calling cdr n times.
+// (nthcdr 1 '(1 2 3)) => (2 3)
+func builtinNthcdr(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("nthcdr needs two arguments")
+	}
+
+	n, err := indexArg("nthcdr", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nthcdr("nthcdr", n, args[1])
+}
+
+// builtinNth returns the value at an index of a list starting from 0.
+// (nth 1 '(1 2 3)) => 2
+func builtinNth(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("nth needs two arguments")
+	}
+
+	n, err := indexArg("nth", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := nthcdr("nth", n, args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return car("nth", e)
+}
+
+// builtinLast returns the last cons of a list.
+// (last '(1 2 3)) => (3)
+func builtinLast(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("last needs one argument")
+	}
+
+	e := args[0]
+
+	if !isList(e) {
+		return nil, fmt.Errorf("last needs a list got: %s", e)
+	}
+
+	var cycle cycleCheck
+
+	for {
+		cons, ok := e.(*syntax.ConsExpr)
+
+		if !ok {
+			return e, nil
+		}
+
+		if cycle.visit(cons) {
+			return nil, fmt.Errorf("last needs a proper list got: %s", args[0])
+		}
+
+		next, ok := cons.Cdr.(*syntax.ConsExpr)
+
+		if !ok {
+			return cons, nil
+		}
+
+		e = next
+	}
+}
+
+// builtinLength returns the number of values in a list or a vector.
+// (length '(1 2 3)) => 3
+func builtinLength(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("length needs one argument")
+	}
+
+	if v, ok := args[0].(*syntax.VectorExpr); ok {
+		return newNumber(int64(len(v.Items))), nil
+	}
+
+	list, err := properList("length", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newNumber(int64(len(list))), nil
+}
+
+// builtinAppend joins lists together. All lists are copied except for
+// the last one which becomes the tail of the result.
+// (append '(1 2) '(3) '(4 5))
+func builtinAppend(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) == 0 {
+		return &syntax.NilExpr{}, nil
+	}
+
+	items := make([]syntax.Sexpr, 0)
+
+	for _, arg := range args[:len(args)-1] {
+		list, err := properList("append", arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, list...)
+	}
+
+	expr := args[len(args)-1]
+
+	for i := len(items) - 1; i >= 0; i-- {
+		expr = &syntax.ConsExpr{Car: items[i], Cdr: expr}
+	}
+
+	return expr, nil
+}
+
+// builtinReverse returns a new list with the values in reverse order.
+// (reverse '(1 2 3)) => (3 2 1)
+func builtinReverse(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("reverse needs one argument")
+	}
+
+	list, err := properList("reverse", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	var expr syntax.Sexpr = &syntax.NilExpr{}
+
+	for _, item := range list {
+		expr = &syntax.ConsExpr{Car: item, Cdr: expr}
+	}
+
+	return expr, nil
+}
+
+// builtinMember returns the tail of a list starting with the first value
+// eql to the item or nil when there is none.
+// (member 2 '(1 2 3)) => (2 3)
+func builtinMember(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("member needs two arguments")
+	}
+
+	e := args[1]
+	var cycle cycleCheck
+
+	for {
+		switch cons := e.(type) {
+		case *syntax.NilExpr:
+			return cons, nil
+		case *syntax.ConsExpr:
+			if cycle.visit(cons) {
+				return nil, fmt.Errorf("member needs a proper list got: %s", args[1])
+			}
+
+			if syntax.Eql(args[0], cons.Car) {
+				return cons, nil
+			}
+			e = cons.Cdr
+		default:
+			return nil, fmt.Errorf("member needs a proper list got: %s", args[1])
+		}
+	}
+}
+
+// builtinAssoc returns the first pair of an association list whose car
+// is eql to the key or nil when there is none.
+// (assoc 'b '((a 1) (b 2))) => (b 2)
+func builtinAssoc(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("assoc needs two arguments")
+	}
+
+	list, err := properList("assoc", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range list {
+		if isNull(item) {
+			continue
+		}
+
+		pair, ok := item.(*syntax.ConsExpr)
+
+		if !ok {
+			return nil, fmt.Errorf("assoc needs a list of pairs got: %s", item)
+		}
+
+		if syntax.Eql(args[0], pair.Car) {
+			return pair, nil
+		}
+	}
+
+	return &syntax.NilExpr{}, nil
+}
+
+// builtinCopyList returns a copy of the conses of a list keeping its
+// values and its tail.
+// (copy-list '(1 2 3))
+func builtinCopyList(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("copy-list needs one argument")
+	}
+
+	if !isList(args[0]) {
+		return nil, fmt.Errorf("copy-list needs a list got: %s", args[0])
+	}
+
+	items := make([]syntax.Sexpr, 0)
+	e := args[0]
+	var cycle cycleCheck
+
+	for cons, ok := e.(*syntax.ConsExpr); ok; cons, ok = e.(*syntax.ConsExpr) {
+		if cycle.visit(cons) {
+			return nil, fmt.Errorf("copy-list needs a proper list got: %s", args[0])
+		}
+
+		items = append(items, cons.Car)
+		e = cons.Cdr
+	}
+
+	for i := len(items) - 1; i >= 0; i-- {
+		e = &syntax.ConsExpr{Car: items[i], Cdr: e}
+	}
+
+	return e, nil
+}
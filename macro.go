@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// formDefmacro defines a macro in the global scope and returns its name.
+// (defmacro inc (x) `(setq ,x (+ ,x 1)))
+func formDefmacro(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 2 {
+		return state{}, fmt.Errorf("defmacro needs a name and a parameter list")
+	}
+
+	symbol, ok := ss[0].(*syntax.SymbolExpr)
+
+	if !ok {
+		return state{}, fmt.Errorf("defmacro name has to be a symbol got: %s", ss[0])
+	}
+
+	l, err := parseParams(ss[1])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	l.Body = ss[2:]
+	l.Scope = s
+
+	m := &scope.MacroExpr{Name: symbol.Name, Lambda: l}
+
+	// bind the unevaluated arguments to the macro parameters and return
+	// the result of the macro body.
+	m.Fn = func(_ *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		return run(s, func(k *continuation) (state, error) {
+			local, bound, err := bindParams(m.Name, l, args, k)
+
+			if err != nil {
+				return state{}, err
+			}
+
+			return enterBindings(k, bound, func(k *continuation) (state, error) {
+				return evalSequence(l.Body, local, k)
+			})
+		})
+	}
+
+	s.Global().Set(symbol, m)
+	return pass(symbol, k)
+}
+
+// macroFor returns the macro called by a s-expression or nil when the
+// s-expression is not a macro call.
+func macroFor(s *scope.Scope, e syntax.Sexpr) *scope.MacroExpr {
+	cons, ok := e.(*syntax.ConsExpr)
+
+	if !ok {
+		return nil
+	}
+
+	symbol, ok := cons.Car.(*syntax.SymbolExpr)
+
+	if !ok {
+		return nil
+	}
+
+	if _, ok := specialForms[symbol.Name]; ok {
+		return nil
+	}
+
+	value, err := s.Get(symbol)
+
+	if err != nil {
+		return nil
+	}
+
+	m, _ := value.(*scope.MacroExpr)
+	return m
+}
+
+// macroexpand1 expands a macro call once. It returns the s-expression
+// unchanged when it is not a macro call.
+func macroexpand1(s *scope.Scope, e syntax.Sexpr) (syntax.Sexpr, bool, error) {
+	m := macroFor(s, e)
+
+	if m == nil {
+		return e, false, nil
+	}
+
+	args, err := listToSlice(e.(*syntax.ConsExpr).Cdr)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	expansion, err := m.Fn(s, args)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return expansion, true, nil
+}
+
+// builtinMacroexpand1 expands a macro call once.
+// (macroexpand-1 '(inc x))
+func builtinMacroexpand1(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("macroexpand-1 needs one argument")
+	}
+
+	e, _, err := macroexpand1(s, args[0])
+	return e, err
+}
+
+// builtinMacroexpand expands a macro call until the result is no longer
+// a macro call.
+// (macroexpand '(inc x))
+func builtinMacroexpand(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("macroexpand needs one argument")
+	}
+
+	e := args[0]
+
+	for {
+		expansion, expanded, err := macroexpand1(s, e)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !expanded {
+			return e, nil
+		}
+
+		e = expansion
+	}
+}
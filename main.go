@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
 )
 
 func main() {
@@ -20,49 +22,73 @@ func main() {
 		scope.Set(k, v)
 	}
 
-	repl := *replPtr
+	input(scope, os.Stdin, os.Stdout, *replPtr)
+}
+
+// input evaluates every line read from r. In REPL mode values are
+// printed and errors open the debugger, otherwise the first error stops
+// the evaluation.
+func input(scope *scope.Scope, r io.Reader, w io.Writer, repl bool) {
+	scanner := bufio.NewScanner(r)
 
 	if repl {
-		for {
-			fmt.Print(">> ")
-			input(scope, true)
-		}
-	} else {
-		input(scope, false)
-	}
-}
+		c := &console{scanner: scanner, out: w}
+		t := threadOf(scope)
+		t.debugger = c.debug
+		defer func() { t.debugger = nil }()
 
-func input(scope *scope.Scope, repl bool) {
+		fmt.Fprint(w, ">> ")
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
 		experssions, err := parse(scanner.Text())
-		if err != nil {
-			fmt.Println(err)
-			break
+		failed := err != nil
+
+		if failed {
+			fmt.Fprintln(w, err)
 		}
 
-		failed := false
 		for _, e := range experssions {
-			e, err = eval(e, scope)
+			e, err = evalTopLevel(e, scope)
 			if err != nil {
-				fmt.Println(err)
+				// the debugger already printed aborted errors.
+				if _, ok := err.(*aborted); !ok {
+					fmt.Fprintln(w, err)
+				}
 				failed = true
 				break
 			}
 
 			if repl && e != nil {
-				fmt.Println(e)
+				fmt.Fprintln(w, syntax.Format(e, printCircle(scope)))
 			}
 
 		}
 
-		if failed {
+		if failed && !repl {
 			break
 		}
 
 		if repl {
-			fmt.Print(">> ")
+			fmt.Fprint(w, ">> ")
 		}
 	}
 }
+
+// evalTopLevel evaluates an expression read by input. When it fails the
+// debugger can evaluate it again.
+func evalTopLevel(e syntax.Sexpr, s *scope.Scope) (syntax.Sexpr, error) {
+	result, err := eval(e, s)
+
+	if err != nil {
+		return invokeDebugger(s, err, &restart{
+			name:        syntax.Intern("retry"),
+			description: "Evaluate the expression again",
+			invoke: func([]syntax.Sexpr) (syntax.Sexpr, error) {
+				return evalTopLevel(e, s)
+			},
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+func TestREPL(t *testing.T) {
+	for _, test := range []struct {
+		input, want string
+	}{
+		{
+			"(+ 1 2)\n",
+			">> 3\n>> ",
+		},
+		{
+			"(car)\nabort\n(+ 1 2)\n",
+			">> Error: car needs one argument\nRestarts:\n" +
+				"  0: [use-value] Return a value from car\n" +
+				"  1: [retry] Call car again\n" +
+				"  2: [abort] Return to the top level\n" +
+				"debug> >> 3\n>> ",
+		},
+		{
+			"(+ x 1)\nuse-value\n2\n(+ x 1)\n1\n5\nx\n",
+			">> Error: Symbol not found in scope: {x}\nRestarts:\n" +
+				"  0: [use-value] Use a value instead of x\n" +
+				"  1: [store-value] Set x to a value and use it\n" +
+				"  2: [retry] Look up x again\n" +
+				"  3: [abort] Return to the top level\n" +
+				"debug> value: 3\n>> Error: Symbol not found in scope: {x}\nRestarts:\n" +
+				"  0: [use-value] Use a value instead of x\n" +
+				"  1: [store-value] Set x to a value and use it\n" +
+				"  2: [retry] Look up x again\n" +
+				"  3: [abort] Return to the top level\n" +
+				"debug> value: 6\n>> 5\n>> ",
+		},
+		{
+			"(defvar *unset*)\n(list *unset*)\nstore-value\n4\n*unset*\n",
+			">> *unset*\n>> Error: Symbol not found in scope: {*unset*}\nRestarts:\n" +
+				"  0: [use-value] Use a value instead of *unset*\n" +
+				"  1: [store-value] Set *unset* to a value and use it\n" +
+				"  2: [retry] Look up *unset* again\n" +
+				"  3: [abort] Return to the top level\n" +
+				"debug> value: (cons 4 nil)\n>> 4\n>> ",
+		},
+		{
+			"(list y)\n9\n2\n3\n",
+			">> Error: Symbol not found in scope: {y}\nRestarts:\n" +
+				"  0: [use-value] Use a value instead of y\n" +
+				"  1: [store-value] Set y to a value and use it\n" +
+				"  2: [retry] Look up y again\n" +
+				"  3: [abort] Return to the top level\n" +
+				"debug> Choose a restart from 0 to 3\n" +
+				"debug> Error: Symbol not found in scope: {y}\nRestarts:\n" +
+				"  0: [use-value] Use a value instead of y\n" +
+				"  1: [store-value] Set y to a value and use it\n" +
+				"  2: [retry] Look up y again\n" +
+				"  3: [abort] Return to the top level\n" +
+				"debug> >> ",
+		},
+		{
+			"(if)\n0\n",
+			">> Error: if needs two or three arguments\nRestarts:\n" +
+				"  0: [retry] Evaluate the expression again\n" +
+				"  1: [abort] Return to the top level\n" +
+				"debug> Error: if needs two or three arguments\nRestarts:\n" +
+				"  0: [retry] Evaluate the expression again\n" +
+				"  1: [abort] Return to the top level\n" +
+				"debug> >> ",
+		},
+		{
+			"(restart-case (error 'oops 1) (use-zero () \"Return zero\" 0) (use-new (v) v))\nuse-new\n(+ 1 1)\n",
+			">> Error: oops: 1\nRestarts:\n" +
+				"  0: [use-zero] Return zero\n" +
+				"  1: [use-new] Invoke use-new (v)\n" +
+				"  2: [abort] Return to the top level\n" +
+				"debug> value: 2\n>> ",
+		},
+		{
+			"(handler-case (car 1) (error (e) e))\n(throw 'a 1)\n(ignore-errors z)\n",
+			">> \"car needs a list got: 1\"\n>> throw with no catch for tag: a\n>> nil\n>> ",
+		},
+	} {
+		var buf bytes.Buffer
+		input(newTestScope(), strings.NewReader(test.input), &buf, true)
+
+		if got := buf.String(); got != test.want {
+			t.Errorf("repl `%s` = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestREPLDebuggerScope(t *testing.T) {
+	s := newTestScope()
+
+	// other evaluates an error in another global scope while the REPL
+	// of s is running, the debugger of s must not open for it.
+	s.Set(syntax.Intern("other"), &scope.FuncExpr{
+		Name: "other",
+		Fn: func(*scope.Scope, []syntax.Sexpr) (syntax.Sexpr, error) {
+			_, err := evalString("(car 1)", newTestScope())
+			return &syntax.AtomExpr{Token: syntax.STRING, Raw: err.Error(), Value: err.Error()}, nil
+		},
+	})
+
+	var buf bytes.Buffer
+	input(s, strings.NewReader("(other)\n"), &buf, true)
+
+	if got, want := buf.String(), ">> \"car needs a list got: 1\"\n>> "; got != want {
+		t.Errorf("repl = %q, want %q", got, want)
+	}
+}
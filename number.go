@@ -0,0 +1,612 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// Numeric kinds ordered from the narrowest to the widest. The arguments
+// of an arithmetic operation are promoted to the widest kind of the two.
+const (
+	intKind = iota
+	bigKind
+	ratioKind
+	floatKind
+)
+
+// A numberOp holds an arithmetic operation for every numeric kind.
+// The int function reports false on overflow so the operation is done
+// again with big integers. Operations without ratio and float functions
+// only take integers.
+type numberOp struct {
+	name      string
+	minKind   int  // narrowest kind the operation is done with
+	checkZero bool // fail when the second argument is zero
+	int       func(a, b int64) (int64, bool)
+	big       func(a, b *big.Int) *big.Int
+	ratio     func(a, b *big.Rat) *big.Rat
+	float     func(a, b float64) float64
+}
+
+var (
+	addOp = numberOp{
+		name: "+",
+		int: func(a, b int64) (int64, bool) {
+			c := a + b
+			return c, (c > a) == (b > 0)
+		},
+		big:   func(a, b *big.Int) *big.Int { return new(big.Int).Add(a, b) },
+		ratio: func(a, b *big.Rat) *big.Rat { return new(big.Rat).Add(a, b) },
+		float: func(a, b float64) float64 { return a + b },
+	}
+
+	subOp = numberOp{
+		name: "-",
+		int: func(a, b int64) (int64, bool) {
+			c := a - b
+			return c, (c < a) == (b > 0)
+		},
+		big:   func(a, b *big.Int) *big.Int { return new(big.Int).Sub(a, b) },
+		ratio: func(a, b *big.Rat) *big.Rat { return new(big.Rat).Sub(a, b) },
+		float: func(a, b float64) float64 { return a - b },
+	}
+
+	mulOp = numberOp{
+		name: "*",
+		int: func(a, b int64) (int64, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+
+			if a == -1 || b == -1 {
+				return a * b, a != math.MinInt64 && b != math.MinInt64
+			}
+
+			c := a * b
+			return c, c/b == a
+		},
+		big:   func(a, b *big.Int) *big.Int { return new(big.Int).Mul(a, b) },
+		ratio: func(a, b *big.Rat) *big.Rat { return new(big.Rat).Mul(a, b) },
+		float: func(a, b float64) float64 { return a * b },
+	}
+
+	// division of integers returns a ratio unless it is exact.
+	divOp = numberOp{
+		name:      "/",
+		minKind:   ratioKind,
+		checkZero: true,
+		ratio:     func(a, b *big.Rat) *big.Rat { return new(big.Rat).Quo(a, b) },
+		float:     func(a, b float64) float64 { return a / b },
+	}
+
+	// quotient truncates toward zero.
+	quotientOp = numberOp{
+		name:      "quotient",
+		checkZero: true,
+		int: func(a, b int64) (int64, bool) {
+			return a / b, a != math.MinInt64 || b != -1
+		},
+		big: func(a, b *big.Int) *big.Int { return new(big.Int).Quo(a, b) },
+	}
+
+	// rem has the sign of the dividend.
+	remOp = numberOp{
+		name:      "rem",
+		checkZero: true,
+		int: func(a, b int64) (int64, bool) {
+			return a % b, true
+		},
+		big: func(a, b *big.Int) *big.Int { return new(big.Int).Rem(a, b) },
+	}
+
+	// mod has the sign of the divisor.
+	modOp = numberOp{
+		name:      "mod",
+		checkZero: true,
+		int: func(a, b int64) (int64, bool) {
+			r := a % b
+			if r != 0 && (r < 0) != (b < 0) {
+				r += b
+			}
+			return r, true
+		},
+		big: func(a, b *big.Int) *big.Int {
+			r := new(big.Int).Rem(a, b)
+			if r.Sign() != 0 && r.Sign() != b.Sign() {
+				r.Add(r, b)
+			}
+			return r
+		},
+	}
+)
+
+// arith applies an arithmetic operation to two numbers.
+func arith(op numberOp, x, y syntax.Sexpr) (syntax.Sexpr, error) {
+	a, b, kind, err := promote(op.name, op.minKind, x, y)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if op.checkZero && isZero(b) {
+		return nil, fmt.Errorf("Division by zero")
+	}
+
+	if kind >= ratioKind && op.ratio == nil {
+		if _, k, _ := numberValue(x); k >= ratioKind {
+			return nil, fmt.Errorf("%s needs integers got: %s", op.name, x)
+		}
+		return nil, fmt.Errorf("%s needs integers got: %s", op.name, y)
+	}
+
+	switch kind {
+	case intKind:
+		if c, ok := op.int(a.(int64), b.(int64)); ok {
+			return newNumber(c), nil
+		}
+		return newNumber(op.big(toBig(a), toBig(b))), nil
+	case bigKind:
+		return newNumber(op.big(a.(*big.Int), b.(*big.Int))), nil
+	case ratioKind:
+		return newNumber(op.ratio(a.(*big.Rat), b.(*big.Rat))), nil
+	}
+	return newNumber(op.float(a.(float64), b.(float64))), nil
+}
+
+// compare returns -1, 0 or 1 when the number x is less, equal or greater
+// than the number y.
+func compare(name string, x, y syntax.Sexpr) (int, error) {
+	a, b, kind, err := promote(name, intKind, x, y)
+
+	if err != nil {
+		return 0, err
+	}
+
+	switch kind {
+	case intKind:
+		switch a, b := a.(int64), b.(int64); {
+		case a < b:
+			return -1, nil
+		case a > b:
+			return 1, nil
+		}
+		return 0, nil
+	case bigKind:
+		return a.(*big.Int).Cmp(b.(*big.Int)), nil
+	case ratioKind:
+		return a.(*big.Rat).Cmp(b.(*big.Rat)), nil
+	}
+
+	switch a, b := a.(float64), b.(float64); {
+	case a < b:
+		return -1, nil
+	case a > b:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// promote returns the values of two numbers converted to the widest of
+// their kinds and minKind.
+func promote(name string, minKind int, x, y syntax.Sexpr) (interface{}, interface{}, int, error) {
+	a, aKind, ok := numberValue(x)
+
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%s needs numbers got: %s", name, x)
+	}
+
+	b, bKind, ok := numberValue(y)
+
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%s needs numbers got: %s", name, y)
+	}
+
+	kind := minKind
+	if aKind > kind {
+		kind = aKind
+	}
+	if bKind > kind {
+		kind = bKind
+	}
+
+	switch kind {
+	case bigKind:
+		return toBig(a), toBig(b), kind, nil
+	case ratioKind:
+		return toRat(a), toRat(b), kind, nil
+	case floatKind:
+		return toFloat(a), toFloat(b), kind, nil
+	}
+	return a, b, kind, nil
+}
+
+// numberValue returns the value of a numeric atom and its kind.
+func numberValue(e syntax.Sexpr) (interface{}, int, bool) {
+	atom, ok := e.(*syntax.AtomExpr)
+
+	if !ok {
+		return nil, 0, false
+	}
+
+	switch v := atom.Value.(type) {
+	case int64:
+		return v, intKind, true
+	case *big.Int:
+		return v, bigKind, true
+	case *big.Rat:
+		return v, ratioKind, true
+	case float64:
+		return v, floatKind, true
+	}
+	return nil, 0, false
+}
+
+// isZero reports whether a number is zero.
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case int64:
+		return v == 0
+	case *big.Int:
+		return v.Sign() == 0
+	case *big.Rat:
+		return v.Sign() == 0
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// toBig converts an integer into a big integer.
+func toBig(v interface{}) *big.Int {
+	if i, ok := v.(int64); ok {
+		return big.NewInt(i)
+	}
+	return v.(*big.Int)
+}
+
+// toRat converts an integer or a ratio into a ratio.
+func toRat(v interface{}) *big.Rat {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(v)
+	case *big.Int:
+		return new(big.Rat).SetInt(v)
+	}
+	return v.(*big.Rat)
+}
+
+// toFloat converts any number into a float.
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	}
+	return v.(float64)
+}
+
+// newNumber wraps a number into an atom using its narrowest kind.
+func newNumber(v interface{}) *syntax.AtomExpr {
+	tok, value := normalizeNumber(v)
+	return &syntax.AtomExpr{Token: tok, Value: value}
+}
+
+// normalizeNumber returns the token and the narrowest value of a number.
+// Big integers that fit in an int64 become int64 and ratios with a
+// denominator of one become integers.
+func normalizeNumber(v interface{}) (syntax.Token, interface{}) {
+	switch n := v.(type) {
+	case *big.Rat:
+		if !n.IsInt() {
+			return syntax.RATIO, n
+		}
+		return normalizeNumber(new(big.Int).Set(n.Num()))
+	case *big.Int:
+		if n.IsInt64() {
+			return syntax.INT, n.Int64()
+		}
+		return syntax.INT, n
+	case float64:
+		return syntax.FLOAT, n
+	}
+	return syntax.INT, v
+}
+
+// numberFunction returns a built-in function applying op to exactly
+// two arguments.
+// (mod 7 3)
+func numberFunction(op numberOp) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s needs two arguments", op.name)
+		}
+
+		return arith(op, args[0], args[1])
+	}
+}
+
+// foldNumbers applies op to init and every argument from left to right.
+func foldNumbers(op numberOp, init syntax.Sexpr, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	result := init
+
+	for _, arg := range args {
+		var err error
+		result, err = arith(op, result, arg)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// builtinAdd adds all the arguments together.
+// (+ 1 2.5 1/2)
+func builtinAdd(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	return foldNumbers(addOp, newNumber(int64(0)), args)
+}
+
+// builtinMul multiplies all the arguments together.
+// (* 2 3 4)
+func builtinMul(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	return foldNumbers(mulOp, newNumber(int64(1)), args)
+}
+
+// builtinSub subtracts the rest of the arguments from the first one. A
+// single argument is negated.
+// (- 10 1 2)
+func builtinSub(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("- needs at least one argument")
+	}
+
+	if len(args) == 1 {
+		return arith(subOp, newNumber(int64(0)), args[0])
+	}
+
+	return foldNumbers(subOp, args[0], args[1:])
+}
+
+// builtinDiv divides the first argument by the rest of the arguments. A
+// single argument returns its reciprocal.
+// (/ 1 3)
+func builtinDiv(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("/ needs at least one argument")
+	}
+
+	if len(args) == 1 {
+		return arith(divOp, newNumber(int64(1)), args[0])
+	}
+
+	return foldNumbers(divOp, args[0], args[1:])
+}
+
+// builtinAbs returns the absolute value of a number.
+// (abs -1/2)
+func builtinAbs(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("abs needs one argument")
+	}
+
+	zero := newNumber(int64(0))
+	c, err := compare("abs", args[0], zero)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c < 0 {
+		return arith(subOp, zero, args[0])
+	}
+
+	return args[0], nil
+}
+
+// extremum returns a built-in function which returns the argument whose
+// comparison with all the others is want.
+// (max 1 3 2)
+func extremum(name string, want int) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("%s needs at least one argument", name)
+		}
+
+		result := args[0]
+
+		if _, _, ok := numberValue(result); !ok {
+			return nil, fmt.Errorf("%s needs numbers got: %s", name, result)
+		}
+
+		for _, arg := range args[1:] {
+			c, err := compare(name, arg, result)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if c == want {
+				result = arg
+			}
+		}
+
+		return result, nil
+	}
+}
+
+// comparison returns a built-in function which is true when every
+// argument compared with the next one satisfies ok.
+// (< 1 2 3)
+func comparison(name string, ok func(c int) bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("%s needs at least one argument", name)
+		}
+
+		if _, _, ok := numberValue(args[0]); !ok {
+			return nil, fmt.Errorf("%s needs numbers got: %s", name, args[0])
+		}
+
+		result := true
+		for i := 1; i < len(args); i++ {
+			c, err := compare(name, args[i-1], args[i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			result = result && ok(c)
+		}
+
+		return boolExpr(result), nil
+	}
+}
+
+// builtinNotEqual is true when no two arguments are equal.
+// (/= 1 2 3)
+func builtinNotEqual(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("/= needs at least one argument")
+	}
+
+	if _, _, ok := numberValue(args[0]); !ok {
+		return nil, fmt.Errorf("/= needs numbers got: %s", args[0])
+	}
+
+	result := true
+	for i := range args {
+		for j := i + 1; j < len(args); j++ {
+			c, err := compare("/=", args[i], args[j])
+
+			if err != nil {
+				return nil, err
+			}
+
+			result = result && c != 0
+		}
+	}
+
+	return boolExpr(result), nil
+}
+
+// builtinZerop is true when a number is zero.
+// (zerop 0.0)
+func builtinZerop(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("zerop needs one argument")
+	}
+
+	c, err := compare("zerop", args[0], newNumber(int64(0)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return boolExpr(c == 0), nil
+}
+
+// parity returns a built-in function which is true when the remainder
+// of an integer divided by two is want.
+// (evenp 4)
+func parity(name string, want int64) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		op := modOp
+		op.name = name
+
+		r, err := arith(op, args[0], newNumber(int64(2)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return boolExpr(r.(*syntax.AtomExpr).Value == want), nil
+	}
+}
+
+// increment returns a built-in function adding delta to its argument.
+// (1+ 2)
+func increment(name string, delta int64) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		op := addOp
+		op.name = name
+
+		return arith(op, args[0], newNumber(delta))
+	}
+}
+
+// builtinExpt raises a number to an integer power.
+// (expt 2 100)
+func builtinExpt(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expt needs two arguments")
+	}
+
+	if _, _, ok := numberValue(args[0]); !ok {
+		return nil, fmt.Errorf("expt needs numbers got: %s", args[0])
+	}
+
+	v, kind, ok := numberValue(args[1])
+
+	if !ok || kind != intKind {
+		return nil, fmt.Errorf("expt needs an integer power got: %s", args[1])
+	}
+
+	power := v.(int64)
+
+	// the smallest power can not be negated.
+	if power == math.MinInt64 {
+		return nil, fmt.Errorf("expt power is out of range got: %s", args[1])
+	}
+
+	negative := power < 0
+
+	if negative {
+		power = -power
+	}
+
+	// exponentiation by squaring
+	var result syntax.Sexpr = newNumber(int64(1))
+	base := args[0]
+
+	for power > 0 {
+		var err error
+
+		if power&1 == 1 {
+			if result, err = arith(mulOp, result, base); err != nil {
+				return nil, err
+			}
+		}
+
+		power >>= 1
+
+		if power > 0 {
+			if base, err = arith(mulOp, base, base); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if negative {
+		return arith(divOp, newNumber(int64(1)), result)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+func TestArith(t *testing.T) {
+	bigInt := func(s string) syntax.Sexpr {
+		b, _ := new(big.Int).SetString(s, 10)
+		return newNumber(b)
+	}
+
+	ratio := func(a, b int64) syntax.Sexpr {
+		return newNumber(big.NewRat(a, b))
+	}
+
+	for _, test := range []struct {
+		op   numberOp
+		x, y syntax.Sexpr
+		want string
+	}{
+		{addOp, newNumber(int64(1)), newNumber(int64(2)), "3"},
+		{addOp, newNumber(int64(9223372036854775807)), newNumber(int64(1)), "9223372036854775808"},
+		{addOp, newNumber(int64(-9223372036854775808)), newNumber(int64(-1)), "-9223372036854775809"},
+		{subOp, newNumber(int64(-9223372036854775808)), newNumber(int64(1)), "-9223372036854775809"},
+		{subOp, newNumber(int64(1)), newNumber(int64(-9223372036854775807)), "9223372036854775808"},
+		{subOp, bigInt("9223372036854775808"), newNumber(int64(1)), "9223372036854775807"},
+		{mulOp, newNumber(int64(4294967296)), newNumber(int64(4294967296)), "18446744073709551616"},
+		{mulOp, newNumber(int64(-9223372036854775808)), newNumber(int64(-1)), "9223372036854775808"},
+		{mulOp, newNumber(int64(-3)), newNumber(int64(-1)), "3"},
+		{mulOp, ratio(2, 3), newNumber(int64(3)), "2"},
+		{mulOp, newNumber(1.5), newNumber(int64(2)), "3.0"},
+		{divOp, newNumber(int64(6)), newNumber(int64(3)), "2"},
+		{divOp, newNumber(int64(1)), newNumber(int64(3)), "1/3"},
+		{divOp, newNumber(int64(-2)), newNumber(int64(4)), "-1/2"},
+		{divOp, ratio(1, 2), ratio(1, 4), "2"},
+		{divOp, newNumber(1.0), newNumber(int64(4)), "0.25"},
+		{divOp, bigInt("18446744073709551616"), newNumber(int64(4294967296)), "4294967296"},
+	} {
+		got, err := arith(test.op, test.x, test.y)
+
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		if got.String() != test.want {
+			t.Errorf("%s %s %s = %s, want %s", test.x, test.op.name, test.y, got, test.want)
+		}
+	}
+}
+
+func TestArithErrors(t *testing.T) {
+	for _, test := range []struct {
+		op   numberOp
+		x, y syntax.Sexpr
+		want string
+	}{
+		{divOp, newNumber(int64(1)), newNumber(int64(0)), "Division by zero"},
+		{divOp, newNumber(1.0), newNumber(0.0), "Division by zero"},
+		{addOp, newNumber(int64(1)), &syntax.NilExpr{}, "+ needs numbers got: nil"},
+	} {
+		_, err := arith(test.op, test.x, test.y)
+
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s %s %s = %v, want %s", test.x, test.op.name, test.y, err, test.want)
+		}
+	}
+}
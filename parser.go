@@ -12,6 +12,7 @@ type parser struct {
 	sc         *scanner
 	tokenName  token
 	tokenValue *value
+	labels     map[int64]syntax.Sexpr // s-expressions labelled with #n=
 }
 
 // parse takes a input and passes to the scanner then
@@ -23,7 +24,7 @@ func parse(src interface{}) (ss []syntax.Sexpr, err error) {
 		}
 	}()
 
-	p := parser{sc: newScanner(src)}
+	p := parser{sc: newScanner(src), labels: make(map[int64]syntax.Sexpr)}
 	p.nextToken()
 
 	for p.tokenName != EOF {
@@ -33,6 +34,28 @@ func parse(src interface{}) (ss []syntax.Sexpr, err error) {
 	return ss, nil
 }
 
+// parseNumber reads a number written like a number literal. It reports
+// false when src is anything else than a single number.
+func parseNumber(src string) (n syntax.Sexpr, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			n, ok = nil, false
+		}
+	}()
+
+	p := parser{sc: newScanner(src)}
+	p.nextToken()
+
+	switch p.tokenName {
+	case INT, RATIO, FLOAT:
+	default:
+		return nil, false
+	}
+
+	n = p.parseAtom()
+	return n, p.tokenName == EOF
+}
+
 // nextToken gets the next token from the scanner and
 // update the token name and value in parser.
 func (p *parser) nextToken() {
@@ -48,13 +71,39 @@ func (p *parser) parseNext() (expr syntax.Sexpr) {
 		expr = p.parseSymbol()
 	case LPAREN:
 		p.nextToken()
+		p.skipSpace()
+
+		if p.isDot() {
+			panic("Parsing error: dotted list needs an expression before the dot")
+		}
+
 		expr = p.parseCons()
 	case FLOAT:
 		expr = p.parseAtom()
 	case INT:
 		expr = p.parseAtom()
+	case RATIO:
+		expr = p.parseAtom()
 	case STRING:
 		expr = p.parseAtom()
+	case CHAR:
+		expr = p.parseAtom()
+	case QUOTE, QUASIQUOTE, UNQUOTE, UNQUOTE_SPLICING:
+		expr = p.parseQuote()
+	case VECTOR:
+		p.nextToken()
+		expr = p.parseVector(RPAREN)
+	case LBRACKET:
+		p.nextToken()
+		expr = p.parseVector(RBRACKET)
+	case RPAREN, RBRACKET:
+		panic(fmt.Sprintf("Parsing error: unexpected %s", p.tokenName))
+	case LABEL:
+		expr = p.parseLabel()
+	case LABEL_REF:
+		expr = p.parseLabelRef()
+	case INVALID:
+		panic(fmt.Sprintf("Parsing error: invalid token %s", p.tokenValue.raw))
 	}
 
 	// make sure we are closing the last parenthese
@@ -67,6 +116,13 @@ func (p *parser) parseNext() (expr syntax.Sexpr) {
 	return
 }
 
+// skipSpace consumes all whitespace and newline tokens.
+func (p *parser) skipSpace() {
+	for p.tokenName == WHITESPACE || p.tokenName == NEWLINE {
+		p.nextToken()
+	}
+}
+
 // consume the next token if its name
 // match the current token.
 func (p *parser) consume(tok token) {
@@ -75,31 +131,50 @@ func (p *parser) consume(tok token) {
 	}
 }
 
-// parseSymbol parses a symbol by wrapping it in a
-// symbolExpr struct then returns a s-expression.
+// parseSymbol parses a symbol by looking it up in the
+// intern table then returns a s-expression.
+// The symbol nil is read as an empty list and t as the true value.
 func (p *parser) parseSymbol() syntax.Sexpr {
-	tok := p.tokenName
 	name := p.tokenValue.raw
 	p.nextToken()
 
 	p.consume(WHITESPACE)
 
-	return &syntax.SymbolExpr{
-		Token: syntax.Token(tok),
-		Name:  name,
+	// true and false are read as t and nil so they are constants too.
+	switch name {
+	case "nil", "false":
+		return &syntax.NilExpr{}
+	case "t", "true":
+		return syntax.True
 	}
+
+	return syntax.Intern(name)
+}
+
+// isDot reports whether the current token is the dot of a dotted list.
+func (p *parser) isDot() bool {
+	return p.tokenName == SYMBOL && p.tokenValue.raw == "."
 }
 
 // parseCons parses everything inside of
 // the parentheses then returning a consExpr as
-// s-expression interface
+// s-expression interface. The expression after a dot
+// becomes the cdr of the last cons.
+// (1 2 . 3)
 func (p *parser) parseCons() syntax.Sexpr {
+	p.skipSpace()
+
 	tok := p.tokenName
 	if tok == RPAREN {
 		p.nextToken()
+		p.consume(WHITESPACE)
 		return &syntax.NilExpr{}
 	}
 
+	if p.isDot() {
+		return p.parseDotted()
+	}
+
 	car := p.parseNext()
 	cdr := p.parseCons()
 
@@ -108,27 +183,176 @@ func (p *parser) parseCons() syntax.Sexpr {
 	return &syntax.ConsExpr{Car: car, Cdr: cdr}
 }
 
-// parseAtom parses all string, integers and float points
-// wrapped in an atomExpr.
+// parseDotted parses the expression after a dot and the closing
+// parenthese.
+func (p *parser) parseDotted() syntax.Sexpr {
+	p.nextToken()
+
+	p.skipSpace()
+
+	if p.tokenName == EOF || p.tokenName == RPAREN || p.isDot() {
+		panic("Parsing error: dotted list needs an expression after the dot")
+	}
+
+	cdr := p.parseNext()
+
+	p.skipSpace()
+
+	if p.tokenName != RPAREN {
+		panic("Parsing error: dotted list needs one expression after the dot")
+	}
+
+	p.nextToken()
+	p.consume(WHITESPACE)
+
+	return cdr
+}
+
+// parseVector parses the values of a vector until the closing token.
+// #(1 2 3) or [1 2 3]
+func (p *parser) parseVector(end token) syntax.Sexpr {
+	items := make([]syntax.Sexpr, 0)
+
+	for {
+		p.skipSpace()
+
+		if p.tokenName == end {
+			break
+		}
+
+		if p.tokenName == EOF {
+			panic("Parsing error: parenthese missing")
+		}
+
+		if p.isDot() {
+			panic("Parsing error: vector can not have a dot")
+		}
+
+		items = append(items, p.parseNext())
+	}
+
+	p.nextToken()
+	p.consume(WHITESPACE)
+
+	return &syntax.VectorExpr{Items: items}
+}
+
+// quoteNames holds the name of the form each quote token expands to.
+var quoteNames = map[token]string{
+	QUOTE:            "quote",
+	QUASIQUOTE:       "quasiquote",
+	UNQUOTE:          "unquote",
+	UNQUOTE_SPLICING: "unquote-splicing",
+}
+
+// parseQuote parses the s-expression after a quote token and wraps it in
+// its quote form.
+// 'x => (quote x)
+func (p *parser) parseQuote() syntax.Sexpr {
+	name := quoteNames[p.tokenName]
+	p.nextToken()
+
+	p.skipSpace()
+
+	if p.tokenName == EOF || p.tokenName == RPAREN {
+		panic(fmt.Sprintf("Parsing error: %s needs an expression", name))
+	}
+
+	expr := p.parseNext()
+
+	return &syntax.ConsExpr{
+		Car: syntax.Intern(name),
+		Cdr: &syntax.ConsExpr{Car: expr, Cdr: &syntax.NilExpr{}},
+	}
+}
+
+// parseLabel parses the s-expression after a label. A labelled list is
+// created before its elements are parsed so references inside of it can
+// point back to it.
+// #1=(a . #1#)
+func (p *parser) parseLabel() syntax.Sexpr {
+	n := p.tokenValue.int
+	p.nextToken()
+
+	if p.tokenName == EOF || p.tokenName == RPAREN || p.tokenName == WHITESPACE {
+		panic(fmt.Sprintf("Parsing error: label #%d= needs an expression", n))
+	}
+
+	if p.tokenName == VECTOR || p.tokenName == LBRACKET {
+		placeholder := &syntax.VectorExpr{}
+		p.labels[n] = placeholder
+
+		*placeholder = *p.parseNext().(*syntax.VectorExpr)
+		return placeholder
+	}
+
+	placeholder := &syntax.ConsExpr{}
+	p.labels[n] = placeholder
+
+	expr := p.parseNext()
+
+	if expr == placeholder {
+		panic(fmt.Sprintf("Parsing error: label #%d= refers to itself", n))
+	}
+
+	cons, ok := expr.(*syntax.ConsExpr)
+
+	if !ok {
+		p.labels[n] = expr
+		return expr
+	}
+
+	*placeholder = *cons
+	return placeholder
+}
+
+// parseLabelRef parses a reference to a labelled s-expression.
+func (p *parser) parseLabelRef() syntax.Sexpr {
+	n := p.tokenValue.int
+	expr, ok := p.labels[n]
+
+	if !ok {
+		panic(fmt.Sprintf("Parsing error: undefined label #%d#", n))
+	}
+
+	p.nextToken()
+	p.consume(WHITESPACE)
+
+	return expr
+}
+
+// parseAtom parses all string, integers, ratios, float points and
+// characters wrapped in an atomExpr.
 func (p *parser) parseAtom() syntax.Sexpr {
 	var value interface{}
 	tok := p.tokenName
 	raw := p.tokenValue.raw
 
+	atomTok := syntax.Token(tok)
+
 	switch tok {
 	case STRING:
 		value = p.tokenValue.string
 	case INT:
 		value = p.tokenValue.int
+
+		if p.tokenValue.big != nil {
+			value = p.tokenValue.big
+		}
+	case RATIO:
+		// ratios like 4/2 are read as integers.
+		atomTok, value = normalizeNumber(p.tokenValue.ratio)
 	case FLOAT:
 		value = p.tokenValue.float
+	case CHAR:
+		value = p.tokenValue.char
 	}
 	p.nextToken()
 
 	p.consume(WHITESPACE)
 
 	return &syntax.AtomExpr{
-		Token: syntax.Token(tok),
+		Token: atomTok,
 		Raw:   raw,
 		Value: value,
 	}
@@ -17,7 +17,27 @@ func TestParser(t *testing.T) {
 		{`(1 (2 3) ())`, "(cons 1 (cons (cons 2 (cons 3 nil)) (cons nil nil)))"},
 		{`(setq c (list 1.4 "1" 3))`, "(cons setq (cons c (cons (cons list (cons 1.4 (cons \"1\" (cons 3 nil)))) nil)))"},
 		{`(())`, "(cons nil nil)"},
-		{`(+ 1.4 5.0)`, "(cons + (cons 1.4 (cons 5 nil)))"},
+		{`(nil)`, "(cons nil nil)"},
+		{`(true false)`, "(cons t (cons nil nil))"},
+		{`(() 1)`, "(cons nil (cons 1 nil))"},
+		{`'x`, "(cons quote (cons x nil))"},
+		{`'(1 x)`, "(cons quote (cons (cons 1 (cons x nil)) nil))"},
+		{`(list ' x 'y)`, "(cons list (cons (cons quote (cons x nil)) (cons (cons quote (cons y nil)) nil)))"},
+		{"`(a ,b ,@c)", "(cons quasiquote (cons (cons a (cons (cons unquote (cons b nil)) (cons (cons unquote-splicing (cons c nil)) nil))) nil))"},
+		{`(+ 1.4 5.0)`, "(cons + (cons 1.4 (cons 5.0 nil)))"},
+		{`(1.0 -0.5 1e21 1e-7)`, "(cons 1.0 (cons -0.5 (cons 1e+21 (cons 1e-07 nil))))"},
+		{`(2/6 4/2 99999999999999999999)`, "(cons 1/3 (cons 2 (cons 99999999999999999999 nil)))"},
+		{`(-7 010 -2/6 -99999999999999999999)`, "(cons -7 (cons 10 (cons -1/3 (cons -99999999999999999999 nil))))"},
+		{`(a . b)`, "(cons a b)"},
+		{`(1 2 . 3)`, "(cons 1 (cons 2 3))"},
+		{"(1 .\n(2))", "(cons 1 (cons 2 nil))"},
+		{`((a . 1) (b . 2))`, "(cons (cons a 1) (cons (cons b 2) nil))"},
+		{`(a ... b)`, "(cons a (cons ... (cons b nil)))"},
+		{`( 1 2 )`, "(cons 1 (cons 2 nil))"},
+		{"(1\n  2)", "(cons 1 (cons 2 nil))"},
+		{`#(1 (2) [3])`, "#(1 (cons 2 nil) #(3))"},
+		{`[ 1 2 ]`, "#(1 2)"},
+		{`'[a]`, "(cons quote (cons #(a) nil))"},
 	} {
 		expr, err := parse(test.input)
 
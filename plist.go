@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// symbolArg returns a s-expression as a symbol which can hold properties.
+func symbolArg(name string, e syntax.Sexpr) (*syntax.SymbolExpr, error) {
+	symbol, ok := e.(*syntax.SymbolExpr)
+
+	if !ok {
+		return nil, fmt.Errorf("%s needs a symbol got: %s", name, e)
+	}
+
+	return symbol, nil
+}
+
+// builtinGet returns the value of a property of a symbol or nil when it
+// is not set.
+// (get 'apple 'color)
+func builtinGet(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("get needs two arguments")
+	}
+
+	symbol, err := symbolArg("get", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := symbol.Property(args[1]); ok {
+		return value, nil
+	}
+
+	return &syntax.NilExpr{}, nil
+}
+
+// builtinPut sets the value of a property of a symbol and returns the
+// value.
+// (put 'apple 'color 'red)
+func builtinPut(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("put needs three arguments")
+	}
+
+	symbol, err := symbolArg("put", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	symbol.SetProperty(args[1], args[2])
+	return args[2], nil
+}
+
+// builtinRemprop deletes a property of a symbol and returns whether it
+// was set.
+// (remprop 'apple 'color)
+func builtinRemprop(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("remprop needs two arguments")
+	}
+
+	symbol, err := symbolArg("remprop", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return boolExpr(symbol.RemoveProperty(args[1])), nil
+}
+
+// builtinSymbolPlist returns the property list of a symbol.
+// (symbol-plist 'apple) => (color red)
+func builtinSymbolPlist(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("symbol-plist needs one argument")
+	}
+
+	symbol, err := symbolArg("symbol-plist", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return symbol.Plist(), nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// predicate returns a built-in function of one argument which is true
+// when test is true for the argument.
+// (consp x)
+func predicate(name string, test func(syntax.Sexpr) bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		return boolExpr(test(args[0])), nil
+	}
+}
+
+// equality returns a built-in function of two arguments which is true
+// when test is true for them.
+// (equal x y)
+func equality(name string, test func(a, b syntax.Sexpr) bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s needs two arguments", name)
+		}
+
+		return boolExpr(test(args[0], args[1])), nil
+	}
+}
+
+// isNull reports whether a s-expression is nil.
+func isNull(e syntax.Sexpr) bool {
+	return !isTrue(e)
+}
+
+// isAtom reports whether a s-expression is anything but a cons.
+func isAtom(e syntax.Sexpr) bool {
+	return !isCons(e)
+}
+
+// isCons reports whether a s-expression is a cons.
+func isCons(e syntax.Sexpr) bool {
+	_, ok := e.(*syntax.ConsExpr)
+	return ok
+}
+
+// isList reports whether a s-expression is a cons or nil.
+func isList(e syntax.Sexpr) bool {
+	return isCons(e) || isNull(e)
+}
+
+// isSymbolExpr reports whether a s-expression is a symbol. nil and t
+// are symbols too.
+func isSymbolExpr(e syntax.Sexpr) bool {
+	switch e.(type) {
+	case *syntax.SymbolExpr, *syntax.TrueExpr:
+		return true
+	}
+	return isNull(e)
+}
+
+// isNumber reports whether a s-expression is a number.
+func isNumber(e syntax.Sexpr) bool {
+	_, _, ok := numberValue(e)
+	return ok
+}
+
+// isInteger reports whether a s-expression is an integer of any size.
+func isInteger(e syntax.Sexpr) bool {
+	_, kind, ok := numberValue(e)
+	return ok && kind <= bigKind
+}
+
+// isFloat reports whether a s-expression is a float.
+func isFloat(e syntax.Sexpr) bool {
+	_, kind, ok := numberValue(e)
+	return ok && kind == floatKind
+}
+
+// isString reports whether a s-expression is a string.
+func isString(e syntax.Sexpr) bool {
+	atom, ok := e.(*syntax.AtomExpr)
+	return ok && atom.Token == syntax.STRING
+}
+
+// isChar reports whether a s-expression is a character.
+func isChar(e syntax.Sexpr) bool {
+	atom, ok := e.(*syntax.AtomExpr)
+	return ok && atom.Token == syntax.CHAR
+}
+
+// isFunction reports whether a s-expression can be called as a function.
+func isFunction(e syntax.Sexpr) bool {
+	switch e.(type) {
+	case *scope.FuncExpr, *continuationExpr:
+		return true
+	}
+	return false
+}
+
+// isHashTable reports whether a s-expression is a hash table.
+func isHashTable(e syntax.Sexpr) bool {
+	_, ok := e.(*syntax.HashTableExpr)
+	return ok
+}
+
+// isVector reports whether a s-expression is a vector.
+func isVector(e syntax.Sexpr) bool {
+	_, ok := e.(*syntax.VectorExpr)
+	return ok
+}
+
+// typeOf returns the name of the type of a s-expression.
+func typeOf(e syntax.Sexpr) string {
+	switch e := e.(type) {
+	case *syntax.ConsExpr:
+		return "cons"
+	case *syntax.TrueExpr:
+		return "boolean"
+	case *syntax.SymbolExpr:
+		return "symbol"
+	case *scope.FuncExpr:
+		return "function"
+	case *scope.MacroExpr:
+		return "macro"
+	case *continuationExpr:
+		return "continuation"
+	case *syntax.HashTableExpr:
+		return "hash-table"
+	case *syntax.VectorExpr:
+		return "vector"
+	case *syntax.AtomExpr:
+		switch e.Token {
+		case syntax.STRING:
+			return "string"
+		case syntax.CHAR:
+			return "character"
+		}
+
+		switch _, kind, _ := numberValue(e); kind {
+		case ratioKind:
+			return "ratio"
+		case floatKind:
+			return "float"
+		}
+		return "integer"
+	}
+	return "null"
+}
+
+// builtinTypeOf returns a symbol naming the type of its argument.
+// (type-of 1.5) => float
+func builtinTypeOf(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("type-of needs one argument")
+	}
+
+	return syntax.Intern(typeOf(args[0])), nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// formQuote returns its argument without evaluating it.
+// (quote (1 2 3)) or '(1 2 3)
+func formQuote(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) != 1 {
+		return state{}, fmt.Errorf("quote needs one argument")
+	}
+
+	return pass(ss[0], k)
+}
+
+// formQuasiquote returns its argument without evaluating it except for
+// the parts marked with unquote or unquote-splicing. The argument is
+// turned into an expression building it which is evaluated in its place.
+// `(1 ,(+ 1 1) ,@(list 3 4)) => (1 2 3 4)
+func formQuasiquote(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) != 1 {
+		return state{}, fmt.Errorf("quasiquote needs one argument")
+	}
+
+	expr, err := quasiquote(ss[0], 1)
+
+	if err != nil {
+		return state{}, err
+	}
+
+	return evaluate(expr, s, k)
+}
+
+// formUnquote fails since unquote is only valid inside a quasiquote.
+func formUnquote(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return state{}, fmt.Errorf("unquote outside of quasiquote")
+}
+
+// formUnquoteSplicing fails since unquote-splicing is only valid inside
+// a quasiquote.
+func formUnquoteSplicing(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return state{}, fmt.Errorf("unquote-splicing outside of quasiquote")
+}
+
+var (
+	// quasiquoteList creates a list of the values of a quasiquote.
+	quasiquoteList = &scope.FuncExpr{Name: "quasiquote", Fn: builtinList}
+
+	// quasiquoteAppend copies the lists of a quasiquote into one
+	// list ending with its last argument.
+	quasiquoteAppend = &scope.FuncExpr{Name: "quasiquote", Fn: func(_ *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		items := make([]syntax.Sexpr, 0)
+
+		for _, e := range args[:len(args)-1] {
+			list, err := listToSlice(e)
+
+			if err != nil {
+				return nil, fmt.Errorf("unquote-splicing needs a list got: %s", e)
+			}
+
+			items = append(items, list...)
+		}
+
+		tail := args[len(args)-1]
+
+		for i := len(items) - 1; i >= 0; i-- {
+			tail = &syntax.ConsExpr{Car: items[i], Cdr: tail}
+		}
+
+		return tail, nil
+	}}
+)
+
+// quasiquote returns an expression building a quasiquoted s-expression.
+// The depth is increased by every nested quasiquote and decreased by
+// every unquote so only the unquotes at depth one get evaluated.
+func quasiquote(e syntax.Sexpr, depth int) (syntax.Sexpr, error) {
+	cons, ok := e.(*syntax.ConsExpr)
+
+	if !ok {
+		return quoted(e), nil
+	}
+
+	switch quoteForm(cons) {
+	case "unquote":
+		arg, err := quoteArg(cons)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if depth == 1 {
+			return arg, nil
+		}
+
+		return quasiquoteForm("unquote", arg, depth-1)
+	case "quasiquote":
+		arg, err := quoteArg(cons)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return quasiquoteForm("quasiquote", arg, depth+1)
+	case "unquote-splicing":
+		arg, err := quoteArg(cons)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if depth == 1 {
+			return nil, fmt.Errorf("unquote-splicing has to be inside a list")
+		}
+
+		return quasiquoteForm("unquote-splicing", arg, depth-1)
+	}
+
+	// every element is a list of one value or a spliced list.
+	lists := make([]syntax.Sexpr, 0)
+
+	for {
+		cons, ok := e.(*syntax.ConsExpr)
+
+		// nil, a non-list tail or a tail written as `(a . ,b) are
+		// expanded on their own.
+		if !ok || quoteForm(cons) == "unquote" {
+			tail, err := quasiquote(e, depth)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return call(quasiquoteAppend, append(lists, tail)...), nil
+		}
+
+		item, ok := cons.Car.(*syntax.ConsExpr)
+
+		if ok && depth == 1 && quoteForm(item) == "unquote-splicing" {
+			arg, err := quoteArg(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			lists = append(lists, arg)
+		} else {
+			expr, err := quasiquote(cons.Car, depth)
+
+			if err != nil {
+				return nil, err
+			}
+
+			lists = append(lists, call(quasiquoteList, expr))
+		}
+
+		e = cons.Cdr
+	}
+}
+
+// quasiquoteForm returns an expression building arg expanded at the
+// given depth wrapped back in the named quote form.
+func quasiquoteForm(name string, arg syntax.Sexpr, depth int) (syntax.Sexpr, error) {
+	expr, err := quasiquote(arg, depth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return call(quasiquoteList, quoted(syntax.Intern(name)), expr), nil
+}
+
+// quoted returns an expression evaluating to e.
+func quoted(e syntax.Sexpr) syntax.Sexpr {
+	return call(syntax.Intern("quote"), e)
+}
+
+// call returns an expression calling fn with args.
+func call(fn syntax.Sexpr, args ...syntax.Sexpr) syntax.Sexpr {
+	return &syntax.ConsExpr{Car: fn, Cdr: sliceToList(args)}
+}
+
+// quoteForm returns the name of the quote form of a list or an empty
+// string when the list does not start with one.
+func quoteForm(cons *syntax.ConsExpr) string {
+	symbol, ok := cons.Car.(*syntax.SymbolExpr)
+
+	if !ok {
+		return ""
+	}
+
+	switch symbol.Name {
+	case "quote", "quasiquote", "unquote", "unquote-splicing":
+		return symbol.Name
+	}
+	return ""
+}
+
+// quoteArg returns the only argument of a quote form.
+func quoteArg(cons *syntax.ConsExpr) (syntax.Sexpr, error) {
+	list, err := listToSlice(cons.Cdr)
+
+	if err != nil || len(list) != 1 {
+		return nil, fmt.Errorf("%s needs one argument", quoteForm(cons))
+	}
+
+	return list[0], nil
+}
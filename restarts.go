@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// A restart is a way to continue after an error. Restarts are offered
+// by the debugger or invoked with invoke-restart.
+type restart struct {
+	name        *syntax.SymbolExpr
+	description string
+	params      int // number of values read by the debugger
+	invoke      func(args []syntax.Sexpr) (syntax.Sexpr, error)
+}
+
+// A debugger is called with an error which no handler-case handles and
+// the restarts available where it happened. It returns the result of
+// the restart chosen.
+type debugger func(s *scope.Scope, err error, restarts []*restart) (syntax.Sexpr, error)
+
+// An aborted error is returned by the abort restart. It is not a
+// condition so it goes back to the top level without being handled.
+type aborted struct {
+	err error
+}
+
+func (a *aborted) Error() string {
+	return a.err.Error()
+}
+
+// A restartInvocation is returned as an error by a restart of
+// restart-case until the restart-case defining it evaluates it.
+type restartInvocation struct {
+	restart *restart
+	args    []syntax.Sexpr
+}
+
+func (r *restartInvocation) Error() string {
+	return fmt.Sprintf("restart %s invoked outside of its restart-case", r.restart.name.Name)
+}
+
+// debuggable reports whether an error should go to the debugger. Errors
+// handled by a handler-case, throws and restarts are not.
+func debuggable(s *scope.Scope, err error) bool {
+	c, ok := conditionOf(err)
+
+	if !ok {
+		return false
+	}
+
+	return !handled(s, c)
+}
+
+// invokeDebugger calls the debugger with an error, the restarts of the
+// place it happened, the restarts of restart-case forms and abort. It
+// returns the error when the debugger is not enabled or the error is not
+// debuggable.
+func invokeDebugger(s *scope.Scope, err error, restarts ...*restart) (syntax.Sexpr, error) {
+	debug := threadOf(s).debugger
+
+	if debug == nil || !debuggable(s, err) {
+		return nil, err
+	}
+
+	for k := currentContinuation(s); k != nil; k = k.next {
+		if f, ok := k.frame.(*restartFrame); ok {
+			restarts = append(restarts, f.restarts...)
+		}
+	}
+
+	restarts = append(restarts, &restart{
+		name:        syntax.Intern("abort"),
+		description: "Return to the top level",
+		invoke: func([]syntax.Sexpr) (syntax.Sexpr, error) {
+			return nil, &aborted{err}
+		},
+	})
+
+	return debug(s, err, restarts)
+}
+
+// unboundRestarts returns the restarts for a symbol which is not bound.
+func unboundRestarts(s *scope.Scope, symbol *syntax.SymbolExpr) []*restart {
+	return []*restart{
+		{
+			name:        syntax.Intern("use-value"),
+			description: fmt.Sprintf("Use a value instead of %s", symbol.Name),
+			params:      1,
+			invoke: func(args []syntax.Sexpr) (syntax.Sexpr, error) {
+				return args[0], nil
+			},
+		},
+		{
+			name:        syntax.Intern("store-value"),
+			description: fmt.Sprintf("Set %s to a value and use it", symbol.Name),
+			params:      1,
+			invoke: func(args []syntax.Sexpr) (syntax.Sexpr, error) {
+				setSymbol(s, symbol, args[0])
+				return args[0], nil
+			},
+		},
+		{
+			name:        syntax.Intern("retry"),
+			description: fmt.Sprintf("Look up %s again", symbol.Name),
+			invoke: func([]syntax.Sexpr) (syntax.Sexpr, error) {
+				return evalSymbol(symbol, s)
+			},
+		},
+	}
+}
+
+// callRestarts returns the restarts for a built-in function call which
+// failed. Conditions raised by error or signal can not be retried.
+func callRestarts(s *scope.Scope, f *scope.FuncExpr, args []syntax.Sexpr, err error) []*restart {
+	if _, ok := err.(*condition); ok {
+		return nil
+	}
+
+	return []*restart{
+		{
+			name:        syntax.Intern("use-value"),
+			description: fmt.Sprintf("Return a value from %s", f.Name),
+			params:      1,
+			invoke: func(args []syntax.Sexpr) (syntax.Sexpr, error) {
+				return args[0], nil
+			},
+		},
+		{
+			name:        syntax.Intern("retry"),
+			description: fmt.Sprintf("Call %s again", f.Name),
+			invoke: func([]syntax.Sexpr) (syntax.Sexpr, error) {
+				return callBuiltin(s, f, args)
+			},
+		},
+	}
+}
+
+// findRestart returns the innermost restart of restart-case with a name
+// or nil when there is none.
+func findRestart(s *scope.Scope, name *syntax.SymbolExpr) *restart {
+	for k := currentContinuation(s); k != nil; k = k.next {
+		f, ok := k.frame.(*restartFrame)
+
+		if !ok {
+			continue
+		}
+
+		for _, r := range f.restarts {
+			if r.name == name {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// parseRestartClause parses a clause of restart-case. A string before
+// the body describes the restart in the debugger.
+// (use-default () "Use 0" 0)
+func parseRestartClause(s *scope.Scope, e syntax.Sexpr) (*restart, *scope.FuncExpr, error) {
+	list, err := listToSlice(e)
+
+	if err != nil || len(list) < 2 {
+		return nil, nil, fmt.Errorf("restart-case clause needs a name and a parameter list got: %s", e)
+	}
+
+	name, ok := list[0].(*syntax.SymbolExpr)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("restart-case needs a symbol as restart name got: %s", list[0])
+	}
+
+	body := list[2:]
+	f, err := newLambda(name.Name, s, list[1], body)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	description := fmt.Sprintf("Invoke %s %s", name.Name, f.Lambda)
+
+	if len(body) > 1 && isString(body[0]) {
+		description = body[0].(*syntax.AtomExpr).Value.(string)
+	}
+
+	r := &restart{name: name, description: description, params: len(f.Lambda.Params)}
+
+	r.invoke = func(args []syntax.Sexpr) (syntax.Sexpr, error) {
+		return nil, &restartInvocation{restart: r, args: args}
+	}
+
+	return r, f, nil
+}
+
+// A restartFrame is the frame of the expression of restart-case. It
+// calls the body of one of its restarts when it is invoked.
+type restartFrame struct {
+	passFrame
+	s        *scope.Scope
+	restarts []*restart
+	bodies   map[*restart]*scope.FuncExpr
+}
+
+func (f *restartFrame) catch(err error, k *continuation) (state, bool, error) {
+	invocation, ok := err.(*restartInvocation)
+
+	if !ok {
+		return state{}, false, err
+	}
+
+	body, ok := f.bodies[invocation.restart]
+
+	if !ok {
+		return state{}, false, err
+	}
+
+	st, err := applyFunction(f.s, body, invocation.args, k)
+	return st, true, err
+}
+
+// formRestartCase evaluates an expression with restarts which can be
+// invoked while it is evaluated. Invoking a restart returns the value of
+// its body called with the arguments of invoke-restart.
+// (restart-case (parse x) (use-default () 0) (use-new (v) v))
+func formRestartCase(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("restart-case needs an expression")
+	}
+
+	f := &restartFrame{
+		s:        s,
+		restarts: make([]*restart, 0, len(ss)-1),
+		bodies:   make(map[*restart]*scope.FuncExpr, len(ss)-1),
+	}
+
+	for _, e := range ss[1:] {
+		r, body, err := parseRestartClause(s, e)
+
+		if err != nil {
+			return state{}, err
+		}
+
+		f.restarts = append(f.restarts, r)
+		f.bodies[r] = body
+	}
+
+	return evaluate(ss[0], s, k.push(f))
+}
+
+// builtinInvokeRestart invokes the innermost restart of restart-case
+// with a name.
+// (invoke-restart 'use-new 1)
+func builtinInvokeRestart(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("invoke-restart needs a restart name")
+	}
+
+	name, ok := args[0].(*syntax.SymbolExpr)
+
+	if !ok {
+		return nil, fmt.Errorf("invoke-restart needs a symbol got: %s", args[0])
+	}
+
+	r := findRestart(s, name)
+
+	if r == nil {
+		return nil, fmt.Errorf("invoke-restart has no restart named: %s", name.Name)
+	}
+
+	return r.invoke(args[1:])
+}
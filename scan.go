@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/miguel250/lisp-interpreter/syntax"
 )
 
 // token holds all tokens to be parse.
@@ -41,6 +44,39 @@ const (
 
 	// RPAREN )
 	RPAREN
+
+	// QUOTE 'x
+	QUOTE
+
+	// QUASIQUOTE `x
+	QUASIQUOTE
+
+	// UNQUOTE ,x
+	UNQUOTE
+
+	// UNQUOTE_SPLICING ,@x
+	UNQUOTE_SPLICING
+
+	// RATIO atom 1/3
+	RATIO
+
+	// LABEL #1= labels the next s-expression
+	LABEL
+
+	// LABEL_REF #1# refers to a labelled s-expression
+	LABEL_REF
+
+	// VECTOR #( starts a vector
+	VECTOR
+
+	// LBRACKET [ starts a vector
+	LBRACKET
+
+	// RBRACKET ]
+	RBRACKET
+
+	// CHAR atom #\a
+	CHAR
 )
 
 func (t token) String() string {
@@ -49,16 +85,27 @@ func (t token) String() string {
 
 // tokenNames holds all token with their string names.
 var tokenNames = [...]string{
-	EOF:        "end of file",
-	INVALID:    "invalid token",
-	NEWLINE:    "newline",
-	WHITESPACE: "whitespace",
-	SYMBOL:     "symbol",
-	INT:        "int literal",
-	FLOAT:      "float literal",
-	STRING:     "string literal",
-	LPAREN:     "(",
-	RPAREN:     ")",
+	EOF:              "end of file",
+	INVALID:          "invalid token",
+	NEWLINE:          "newline",
+	WHITESPACE:       "whitespace",
+	SYMBOL:           "symbol",
+	INT:              "int literal",
+	FLOAT:            "float literal",
+	STRING:           "string literal",
+	LPAREN:           "(",
+	RPAREN:           ")",
+	QUOTE:            "'",
+	QUASIQUOTE:       "`",
+	UNQUOTE:          ",",
+	UNQUOTE_SPLICING: ",@",
+	RATIO:            "ratio literal",
+	LABEL:            "label",
+	LABEL_REF:        "label reference",
+	VECTOR:           "#(",
+	LBRACKET:         "[",
+	RBRACKET:         "]",
+	CHAR:             "character literal",
 }
 
 // A position what we are reading.
@@ -93,7 +140,10 @@ func newScanner(src interface{}) *scanner {
 type value struct {
 	raw    string   // raw text of token
 	int    int64    // decoded int
+	big    *big.Int // decoded int too big for int64
+	ratio  *big.Rat // decoded ratio
 	float  float64  // decoded float
+	char   rune     // decoded character
 	string string   // decoded string
 	pos    position // start position of token
 }
@@ -123,6 +173,39 @@ func (sc *scanner) nextToken() (*value, token) {
 		sc.next()
 
 		return val, RPAREN
+	case '[':
+		sc.depth++
+		sc.next()
+
+		return val, LBRACKET
+	case ']':
+		if sc.depth == 0 {
+			panic("Parsing error: bracket missing")
+		}
+
+		sc.depth--
+		sc.next()
+
+		return val, RBRACKET
+	}
+
+	// quotes
+	switch c {
+	case '\'':
+		sc.next()
+		return val, QUOTE
+	case '`':
+		sc.next()
+		return val, QUASIQUOTE
+	case ',':
+		sc.next()
+
+		if sc.peek() == '@' {
+			sc.next()
+			return val, UNQUOTE_SPLICING
+		}
+
+		return val, UNQUOTE
 	}
 
 	// Newline. We only need to worry about \n
@@ -156,25 +239,47 @@ func (sc *scanner) nextToken() (*value, token) {
 		return sc.scanString(val, c)
 	}
 
-	// integers and floats atom
-	if isdigit(c) || c == '.' {
+	// vectors #(, labels #1= and label references #1#
+	if c == '#' {
+		return sc.scanHash(val)
+	}
+
+	// integers and floats atom with an optional sign. A dot or a
+	// sign not followed by a digit starts a symbol such as "..." or
+	// "-".
+	if sc.startsNumber() {
 		return sc.scanNumber(val, c)
 	}
 
 	// symbols
 	if isSymbolStart(c) {
-		for isSymbol(c) {
-			sc.next()
-			c = sc.peek()
-		}
-		sc.endToken(val)
-		return val, SYMBOL
+		return sc.scanSymbol(val)
 	}
 
 	sc.next()
+	sc.endToken(val)
 	return val, INVALID
 }
 
+// startsNumber reports whether the next runes are a digit or a dot
+// followed by a digit after an optional sign.
+func (sc *scanner) startsNumber() bool {
+	rest := sc.rest
+	r, size := utf8.DecodeRune(rest)
+
+	if r == '+' || r == '-' {
+		rest = rest[size:]
+		r, size = utf8.DecodeRune(rest)
+	}
+
+	if r == '.' {
+		rest = rest[size:]
+		r, _ = utf8.DecodeRune(rest)
+	}
+
+	return isdigit(r)
+}
+
 // Peek return next rune without consuming it.
 func (sc *scanner) peek() rune {
 	if len(sc.rest) == 0 {
@@ -190,6 +295,21 @@ func (sc *scanner) peek() rune {
 	return r
 }
 
+// peekSecond return the rune after the next one without consuming them.
+func (sc *scanner) peekSecond() rune {
+	if len(sc.rest) == 0 {
+		return 0
+	}
+
+	_, size := utf8.DecodeRune(sc.rest)
+	r, _ := utf8.DecodeRune(sc.rest[size:])
+
+	if len(sc.rest) == size {
+		return 0
+	}
+	return r
+}
+
 // next consumes the next rune and update the current
 // position.
 func (sc *scanner) next() rune {
@@ -225,6 +345,16 @@ func (sc *scanner) endToken(val *value) {
 	}
 }
 
+// scanSymbol collects all runes for a symbol.
+func (sc *scanner) scanSymbol(val *value) (*value, token) {
+	for isSymbol(sc.peek()) {
+		sc.next()
+	}
+
+	sc.endToken(val)
+	return val, SYMBOL
+}
+
 // scanString collects all runes for a string.
 func (sc *scanner) scanString(val *value, quote rune) (*value, token) {
 	sc.next() // handle first quote
@@ -253,10 +383,13 @@ func (sc *scanner) scanString(val *value, quote rune) (*value, token) {
 func (sc *scanner) scanNumber(val *value, c rune) (*value, token) {
 	fraction, exponent := false, false
 
+	if c == '+' || c == '-' {
+		sc.next() // consume sign
+		c = sc.peek()
+	}
+
 	// check if number starts with a dot for decimal.
 	if c == '.' {
-		sc.next()
-		c = sc.peek()
 		fraction = true
 	} else {
 		for isdigit(c) {
@@ -270,18 +403,18 @@ func (sc *scanner) scanNumber(val *value, c rune) (*value, token) {
 			fraction = true
 		} else if c == 'e' || c == 'E' {
 			exponent = true
+		} else if c == '/' && isdigit(sc.peekSecond()) {
+			return sc.scanRatio(val)
+		} else if isSymbol(c) {
+			// symbols can start with digits like 1+
+			return sc.scanSymbol(val)
 		}
 	}
 
 	if fraction {
 		sc.next() // consume dot
-		c = sc.next()
+		c = sc.peek()
 		for isdigit(c) {
-			// Make sure we don't cosume a closing parenthese, spaces or tabs.
-			if next := sc.peek(); next == ')' || next == ' ' || next == '\t' {
-				break
-			}
-
 			sc.next()
 			c = sc.peek()
 		}
@@ -320,10 +453,17 @@ func (sc *scanner) scanNumber(val *value, c rune) (*value, token) {
 		return val, FLOAT
 	}
 
-	// covert value into a integer
+	// covert value into a decimal integer, leading zeros do not
+	// make it octal.
 	var err error
 	s := val.raw
-	val.int, err = strconv.ParseInt(s, 0, 64)
+	val.int, err = strconv.ParseInt(s, 10, 64)
+
+	// integers too big for an int64 are kept as big integers.
+	if err, ok := err.(*strconv.NumError); ok && err.Err == strconv.ErrRange {
+		val.big, _ = new(big.Int).SetString(s, 10)
+		return val, INT
+	}
 
 	if err != nil {
 		panic(err)
@@ -332,6 +472,117 @@ func (sc *scanner) scanNumber(val *value, c rune) (*value, token) {
 	return val, INT
 }
 
+// scanRatio collects the denominator of a ratio after the numerator.
+func (sc *scanner) scanRatio(val *value) (*value, token) {
+	sc.next() // consume slash
+
+	for isdigit(sc.peek()) {
+		sc.next()
+	}
+
+	sc.endToken(val)
+
+	r, ok := new(big.Rat).SetString(val.raw)
+
+	if !ok {
+		panic(fmt.Sprintf("invalid ratio literal: %s", val.raw))
+	}
+
+	val.ratio = r
+	return val, RATIO
+}
+
+// scanHash collects the runes of a vector start, a character, a label
+// or a label reference.
+func (sc *scanner) scanHash(val *value) (*value, token) {
+	sc.next() // consume #
+
+	switch sc.peek() {
+	case '(':
+		sc.depth++
+		sc.next()
+		sc.endToken(val)
+		return val, VECTOR
+	case '\\':
+		return sc.scanChar(val)
+	}
+
+	if !isdigit(sc.peek()) {
+		sc.endToken(val)
+		return val, INVALID
+	}
+
+	for isdigit(sc.peek()) {
+		sc.next()
+	}
+
+	c := sc.peek()
+
+	if c != '=' && c != '#' {
+		sc.endToken(val)
+		return val, INVALID
+	}
+
+	sc.next()
+	sc.endToken(val)
+
+	var err error
+	val.int, err = strconv.ParseInt(val.raw[1:len(val.raw)-1], 10, 64)
+
+	if err != nil {
+		panic(fmt.Sprintf("invalid label: %s", val.raw))
+	}
+
+	if c == '=' {
+		return val, LABEL
+	}
+	return val, LABEL_REF
+}
+
+// scanChar collects the runes of a character literal after the #. A
+// character is a single rune, a name like space or x followed by its
+// hexadecimal code.
+// #\a #\space #\x41
+func (sc *scanner) scanChar(val *value) (*value, token) {
+	sc.next() // consume backslash
+
+	if sc.peek() == 0 {
+		panic("Parsing error: character literal needs a character")
+	}
+
+	first := sc.next()
+
+	if isSymbol(first) {
+		for isSymbol(sc.peek()) {
+			sc.next()
+		}
+	}
+
+	sc.endToken(val)
+	name := val.raw[2:]
+
+	if utf8.RuneCountInString(name) == 1 {
+		val.char = first
+		return val, CHAR
+	}
+
+	if r, ok := syntax.CharNames[strings.ToLower(name)]; ok {
+		val.char = r
+		return val, CHAR
+	}
+
+	if name[0] == 'x' || name[0] == 'X' {
+		code, err := strconv.ParseUint(name[1:], 16, 32)
+
+		if err == nil && utf8.ValidRune(rune(code)) {
+			val.char = rune(code)
+			return val, CHAR
+		}
+	}
+
+	panic(fmt.Sprintf("Parsing error: invalid character %s", val.raw))
+}
+
 // isSymbolStart return true if rune is in list of
 // valid runes a symbol can start with.
 func isSymbolStart(c rune) bool {
@@ -352,6 +603,9 @@ func isSymbolStart(c rune) bool {
 		c == '>' ||
 		c == '~' ||
 		c == '.' ||
+		c == '!' ||
+		c == '?' ||
+		c == ':' ||
 		unicode.IsLetter(c)
 }
 
@@ -24,7 +24,13 @@ func scan(src interface{}) (string, error) {
 		case STRING:
 			fmt.Fprintf(&buf, "%q", val.string)
 		case INT:
-			fmt.Fprintf(&buf, "%d", val.int)
+			if val.big != nil {
+				fmt.Fprintf(&buf, "%d", val.big)
+			} else {
+				fmt.Fprintf(&buf, "%d", val.int)
+			}
+		case RATIO:
+			buf.WriteString(val.ratio.String())
 		case FLOAT:
 			fmt.Fprintf(&buf, "%e", val.float)
 		case EOF:
@@ -50,11 +56,27 @@ func TestScanner(t *testing.T) {
 		{`(y 3.14159265 .1e+1)`, "( y whitespace 3.141593e+00 whitespace 1.000000e+00 ) EOF"},
 		{`(x 2 "3")`, "( x whitespace 2 whitespace \"3\" ) EOF"},
 		{`b^2-4*a*c`, "b^2-4*a*c EOF"},
-		{`+1`, "+1 EOF"},
+		{`+1`, "1 EOF"},
+		{`(-7 -1.5 -1/2 +.5 -1e2 010 - -x 1-)`, "( -7 whitespace -1.500000e+00 whitespace -1/2 whitespace 5.000000e-01 whitespace -1.000000e+02 whitespace 10 whitespace - whitespace -x whitespace 1- ) EOF"},
+		{`-123456789012345678901234567890`, "-123456789012345678901234567890 EOF"},
 		{`+$`, "+$ EOF"},
 		{`(first (list 1 (+ 2 3) 9))`, "( first whitespace ( list whitespace 1 whitespace ( + whitespace 2 whitespace 3 ) whitespace 9 ) ) EOF"},
 		{"(1e-1 1e1)\n(x 2 \"3\")", "( 1.000000e-01 whitespace 1.000000e+01 ) newline ( x whitespace 2 whitespace \"3\" ) EOF"},
 		{`(+ 1.4 5.0)`, "( + whitespace 1.400000e+00 whitespace 5.000000e+00 ) EOF"},
+		{`'(1 x)`, "' ( 1 whitespace x ) EOF"},
+		{`(a ... .5)`, "( a whitespace ... whitespace 5.000000e-01 ) EOF"},
+		{`(1/3 4/2 1/x)`, "( 1/3 whitespace 2/1 whitespace 1/x ) EOF"},
+		{`(1+ 1- 2)`, "( 1+ whitespace 1- whitespace 2 ) EOF"},
+		{`123456789012345678901234567890`, "123456789012345678901234567890 EOF"},
+		{"`(a ,b ,@c)", "` ( a whitespace , b whitespace ,@ c ) EOF"},
+		{`(a . b)`, "( a whitespace . whitespace b ) EOF"},
+		{`(set-car! null?)`, "( set-car! whitespace null? ) EOF"},
+		{`{`, "invalid token EOF"},
+		{`#1=(a . #12#)`, "label ( a whitespace . whitespace label reference ) EOF"},
+		{`#x`, "invalid token x EOF"},
+		{`(:key x)`, "( :key whitespace x ) EOF"},
+		{`#(1 [2])`, "#( 1 whitespace [ 2 ] ) EOF"},
+		{`(#\a #\space #\))`, "( character literal whitespace character literal whitespace character literal ) EOF"},
 	} {
 
 		got, err := scan(test.input)
@@ -3,22 +3,24 @@ package scope
 import (
 	"bytes"
 	"fmt"
+	"sort"
 
 	"github.com/miguel250/lisp-interpreter/syntax"
 )
 
 // A Scope holds the current set of symbols available
-// for a s-expression.
+// for a s-expression. Symbols are looked up by identity.
 type Scope struct {
-	data   map[syntax.SymbolExpr]syntax.Sexpr
-	parent *Scope
+	data    map[*syntax.SymbolExpr]syntax.Sexpr
+	parent  *Scope
+	context interface{} // set on the outermost scope only
 }
 
 // NewScope returns a new instance of a scope.
 // It can take a parent scope for nesting scope.
 func NewScope(parent *Scope) *Scope {
 	return &Scope{
-		data:   make(map[syntax.SymbolExpr]syntax.Sexpr),
+		data:   make(map[*syntax.SymbolExpr]syntax.Sexpr),
 		parent: parent,
 	}
 }
@@ -28,45 +30,173 @@ func (s *Scope) String() string {
 	if s.parent != nil {
 		fmt.Fprintf(&buf, "parent(%s) ", s.parent)
 	}
-	fmt.Fprintf(&buf, "data: %v", s.data)
+
+	names := make([]string, 0, len(s.data))
+	for symbol, expr := range s.data {
+		names = append(names, fmt.Sprintf("%s:%s", symbol.Name, expr))
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(&buf, "data: %v", names)
 	return buf.String()
 }
 
 // Set adds a symbol and s-expression into the scope.
-func (s *Scope) Set(symbol syntax.SymbolExpr, expr syntax.Sexpr) {
+func (s *Scope) Set(symbol *syntax.SymbolExpr, expr syntax.Sexpr) {
 	s.data[symbol] = expr
 }
 
+// Update changes the value of a symbol in the nearest scope where it is
+// defined. A symbol not defined anywhere is added to the outermost scope
+// without its mark so code outside of the macro expansion sees it.
+func (s *Scope) Update(symbol *syntax.SymbolExpr, expr syntax.Sexpr) {
+	found, key := s.resolve(symbol)
+
+	if found == nil {
+		found, key = s.Global(), symbol.Unmarked()
+	}
+
+	found.data[key] = expr
+}
+
+// Global returns the outermost scope.
+func (s *Scope) Global() *Scope {
+	global := s
+	for global.parent != nil {
+		global = global.parent
+	}
+	return global
+}
+
+// Context returns the value attached to the outermost scope by
+// SetContext or nil.
+func (s *Scope) Context() interface{} {
+	return s.Global().context
+}
+
+// SetContext attaches a value to the outermost scope, every scope nested
+// in it shares the value. The interpreter keeps the state of its
+// evaluation there.
+func (s *Scope) SetContext(c interface{}) {
+	s.Global().context = c
+}
+
 // Get returns a s-expression from a symbol.
-func (s *Scope) Get(symbol syntax.SymbolExpr) (syntax.Sexpr, error) {
-	v, ok := s.data[symbol]
+func (s *Scope) Get(symbol *syntax.SymbolExpr) (syntax.Sexpr, error) {
+	found, key := s.resolve(symbol)
 
-	if ok {
-		return v, nil
+	if found == nil {
+		return nil, fmt.Errorf("Symbol not found in scope: {%s}", symbol.Name)
 	}
 
-	if s.parent != nil {
-		v, err := s.parent.Get(symbol)
+	return found.data[key], nil
+}
+
+// resolve returns the nearest scope where a symbol is defined and the
+// symbol it is defined as. A symbol renamed by a macro expansion which is
+// not bound by the expansion itself refers to the symbol without its
+// mark. It returns a nil scope when the symbol is not defined.
+func (s *Scope) resolve(symbol *syntax.SymbolExpr) (*Scope, *syntax.SymbolExpr) {
+	if found := s.find(symbol); found != nil {
+		return found, symbol
+	}
 
-		if err == nil {
-			return v, nil
+	if unmarked := symbol.Unmarked(); unmarked != symbol {
+		if found := s.find(unmarked); found != nil {
+			return found, unmarked
 		}
 	}
 
-	return nil, fmt.Errorf("Symbol not found in scope: {%s}", symbol.Name)
+	return nil, symbol
+}
+
+// find returns the nearest scope where a symbol is defined.
+func (s *Scope) find(symbol *syntax.SymbolExpr) *Scope {
+	for current := s; current != nil; current = current.parent {
+		if _, ok := current.data[symbol]; ok {
+			return current
+		}
+	}
+	return nil
 }
 
 // Function is a function to be added to the scope and make it accessible to be called.
+// It receives the arguments already evaluated.
 type Function func(*Scope, []syntax.Sexpr) (syntax.Sexpr, error)
 
 // A FuncExpr represent a callable function s-expression.
 type FuncExpr struct {
-	Name string
-	Fn   Function
+	Name   string
+	Fn     Function
+	Lambda *Lambda // nil for go functions
 }
 
 // Expr is use to satified Sexpr interface
 func (*FuncExpr) Expr() {}
 func (f FuncExpr) String() string {
+	if f.Lambda != nil {
+		return fmt.Sprintf("fn: %s %s", f.Name, f.Lambda)
+	}
 	return fmt.Sprintf("fn: %s", f.Name)
 }
+
+// A MacroExpr represent a macro. It is called with its arguments
+// unevaluated and returns a s-expression which is evaluated in its place.
+type MacroExpr struct {
+	Name   string
+	Fn     Function
+	Lambda *Lambda // nil for syntax-rules macros
+}
+
+// Expr is use to satified Sexpr interface
+func (*MacroExpr) Expr() {}
+func (m *MacroExpr) String() string {
+	if m.Lambda != nil {
+		return fmt.Sprintf("macro: %s %s", m.Name, m.Lambda)
+	}
+	return fmt.Sprintf("macro: %s", m.Name)
+}
+
+// A Lambda holds the parameters, body and defining scope of a
+// user-defined function.
+type Lambda struct {
+	Params   []*syntax.SymbolExpr
+	Optional []*syntax.SymbolExpr // parameters after &optional
+	Rest     *syntax.SymbolExpr   // parameter after &rest
+	Body     []syntax.Sexpr
+	Scope    *Scope // scope the function was defined in
+}
+
+// String returns the parameter list of the function.
+// (x y &optional z &rest r)
+func (l *Lambda) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("(")
+
+	for i, p := range l.Params {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(p.Name)
+	}
+
+	if len(l.Optional) > 0 {
+		if len(l.Params) > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString("&optional")
+		for _, p := range l.Optional {
+			fmt.Fprintf(&buf, " %s", p.Name)
+		}
+	}
+
+	if l.Rest != nil {
+		if len(l.Params) > 0 || len(l.Optional) > 0 {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(&buf, "&rest %s", l.Rest.Name)
+	}
+
+	buf.WriteString(")")
+	return buf.String()
+}
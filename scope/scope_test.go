@@ -7,7 +7,7 @@ import (
 )
 
 type testScope struct {
-	symbol      syntax.SymbolExpr
+	symbol      *syntax.SymbolExpr
 	atom        syntax.AtomExpr
 	parent      *testScope
 	scopeString string
@@ -16,21 +16,21 @@ type testScope struct {
 func TestScope(t *testing.T) {
 	for _, test := range []testScope{
 		{
-			syntax.SymbolExpr{Token: syntax.SYMBOL, Name: "x"},
+			syntax.Intern("x"),
 			syntax.AtomExpr{Token: syntax.INT, Raw: "2", Value: 2},
 			nil,
-			"data: map[{4 x}:atom(2)]",
+			"data: [x:2]",
 		},
 		{
-			syntax.SymbolExpr{Token: syntax.SYMBOL, Name: "z"},
+			syntax.Intern("z"),
 			syntax.AtomExpr{Token: syntax.STRING, Raw: "hello", Value: "hello"},
 			&testScope{
-				syntax.SymbolExpr{Token: syntax.SYMBOL, Name: "x"},
+				syntax.Intern("x"),
 				syntax.AtomExpr{Token: syntax.INT, Raw: "2", Value: 2},
 				nil,
 				"",
 			},
-			"parent(data: map[{4 x}:atom(2)]) data: map[{4 z}:atom(\"hello\")]",
+			"parent(data: [x:2]) data: [z:\"hello\"]",
 		},
 	} {
 		s := NewScope(nil)
@@ -72,3 +72,132 @@ func TestScope(t *testing.T) {
 		}
 	}
 }
+
+func TestScopeUpdate(t *testing.T) {
+	x := syntax.Intern("x")
+	y := syntax.Intern("y")
+
+	global := NewScope(nil)
+	global.Set(x, &syntax.AtomExpr{Token: syntax.INT, Raw: "1", Value: int64(1)})
+
+	local := NewScope(NewScope(global))
+	local.Update(x, &syntax.AtomExpr{Token: syntax.INT, Raw: "2", Value: int64(2)})
+	local.Update(y, &syntax.AtomExpr{Token: syntax.INT, Raw: "3", Value: int64(3)})
+
+	for _, test := range []struct {
+		symbol *syntax.SymbolExpr
+		want   string
+	}{
+		{x, "2"},
+		{y, "3"},
+	} {
+		expr, err := global.Get(test.symbol)
+
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		if got := expr.String(); got != test.want {
+			t.Errorf("got: %s want %s", got, test.want)
+		}
+	}
+}
+
+func TestScopeMark(t *testing.T) {
+	x := syntax.Intern("x")
+	marked := syntax.Rename(x, 1)
+
+	global := NewScope(nil)
+	global.Set(x, &syntax.AtomExpr{Token: syntax.INT, Raw: "1", Value: int64(1)})
+
+	// a marked symbol falls back to the unmarked one when it is not bound
+	expr, err := global.Get(marked)
+
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if got := expr.String(); got != "1" {
+		t.Errorf("got: %s want 1", got)
+	}
+
+	global.Update(marked, &syntax.AtomExpr{Token: syntax.INT, Raw: "2", Value: int64(2)})
+
+	// a marked symbol which is not bound anywhere sets the unmarked one
+	y := syntax.Intern("y")
+	global.Update(syntax.Rename(y, 1), &syntax.AtomExpr{Token: syntax.INT, Raw: "4", Value: int64(4)})
+
+	if expr, err := global.Get(y); err != nil || expr.String() != "4" {
+		t.Errorf("got: %v %v want 4", expr, err)
+	}
+
+	// a marked binding does not capture the unmarked symbol
+	local := NewScope(global)
+	local.Set(marked, &syntax.AtomExpr{Token: syntax.INT, Raw: "3", Value: int64(3)})
+
+	for _, test := range []struct {
+		symbol *syntax.SymbolExpr
+		want   string
+	}{
+		{x, "2"},
+		{marked, "3"},
+	} {
+		expr, err := local.Get(test.symbol)
+
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		if got := expr.String(); got != test.want {
+			t.Errorf("got: %s want %s", got, test.want)
+		}
+	}
+}
+
+func TestScopeIdentity(t *testing.T) {
+	x := syntax.Intern("x")
+
+	if x != syntax.Intern("x") {
+		t.Errorf("Intern returned two symbols named x")
+	}
+
+	global := NewScope(nil)
+	global.Set(x, &syntax.AtomExpr{Token: syntax.INT, Raw: "1", Value: int64(1)})
+
+	// a symbol which is not interned is a different symbol
+	if _, err := global.Get(syntax.NewSymbol("x")); err == nil {
+		t.Errorf("uninterned symbol x found in scope")
+	}
+}
+
+func TestScopeGlobal(t *testing.T) {
+	global := NewScope(nil)
+	local := NewScope(NewScope(global))
+
+	if local.Global() != global {
+		t.Errorf("Global did not return the outermost scope")
+	}
+
+	if global.Global() != global {
+		t.Errorf("Global of the outermost scope is not itself")
+	}
+}
+
+func TestScopeContext(t *testing.T) {
+	global := NewScope(nil)
+	local := NewScope(NewScope(global))
+
+	if local.Context() != nil {
+		t.Errorf("Context of a new scope is not nil")
+	}
+
+	local.SetContext("state")
+
+	if global.Context() != "state" {
+		t.Errorf("SetContext did not attach the value to the outermost scope")
+	}
+
+	if NewScope(nil).Context() != nil {
+		t.Errorf("Context is shared by scopes which are not nested")
+	}
+}
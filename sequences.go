@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// functionAndLists checks the arguments of a function taking a function
+// and one or more lists and returns the elements of each list.
+func functionAndLists(name string, args []syntax.Sexpr) ([][]syntax.Sexpr, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s needs a function and a list", name)
+	}
+
+	lists := make([][]syntax.Sexpr, 0, len(args)-1)
+
+	for _, arg := range args[1:] {
+		list, err := properList(name, arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, list)
+	}
+
+	return lists, nil
+}
+
+// mapLists calls fn with the nth element of every list until the shortest
+// list runs out and returns the results. When stop is set, mapping ends
+// at the first result for which stop is true.
+func mapLists(s *scope.Scope, fn syntax.Sexpr, lists [][]syntax.Sexpr, stop func(syntax.Sexpr) bool) ([]syntax.Sexpr, error) {
+	n := len(lists[0])
+
+	for _, list := range lists[1:] {
+		if len(list) < n {
+			n = len(list)
+		}
+	}
+
+	results := make([]syntax.Sexpr, 0, n)
+
+	for i := 0; i < n; i++ {
+		args := make([]syntax.Sexpr, len(lists))
+
+		for j, list := range lists {
+			args[j] = list[i]
+		}
+
+		result, err := apply(s, fn, args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+
+		if stop != nil && stop(result) {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// builtinMapcar returns a list with the results of calling a function on
+// the elements of one or more lists.
+// (mapcar '+ '(1 2) '(10 20)) => (11 22)
+func builtinMapcar(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	lists, err := functionAndLists("mapcar", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := mapLists(s, args[0], lists, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sliceToList(results), nil
+}
+
+// builtinMapc calls a function on the elements of one or more lists for
+// its side effects and returns the first list.
+// (mapc 'print '(1 2))
+func builtinMapc(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	lists, err := functionAndLists("mapc", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := mapLists(s, args[0], lists, nil); err != nil {
+		return nil, err
+	}
+
+	return args[1], nil
+}
+
+// some returns a built-in function which calls a function on the elements
+// of one or more lists until the truth of a result matches keep. some
+// returns that result and every returns whether no result was false.
+// (some 'evenp '(1 2 3)) => t
+func some(name string, keep bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		lists, err := functionAndLists(name, args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := mapLists(s, args[0], lists, func(e syntax.Sexpr) bool {
+			return isTrue(e) == keep
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if keep {
+			if len(results) > 0 && isTrue(results[len(results)-1]) {
+				return results[len(results)-1], nil
+			}
+			return &syntax.NilExpr{}, nil
+		}
+
+		return boolExpr(len(results) == 0 || isTrue(results[len(results)-1])), nil
+	}
+}
+
+// filter returns a built-in function which returns a new list with the
+// elements of a list for which the truth of a function matches keep.
+// (filter 'oddp '(1 2 3)) => (1 3)
+func filter(name string, keep bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s needs a function and a list", name)
+		}
+
+		list, err := properList(name, args[1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]syntax.Sexpr, 0, len(list))
+
+		for _, item := range list {
+			result, err := apply(s, args[0], []syntax.Sexpr{item})
+
+			if err != nil {
+				return nil, err
+			}
+
+			if isTrue(result) == keep {
+				items = append(items, item)
+			}
+		}
+
+		return sliceToList(items), nil
+	}
+}
+
+// builtinFindIf returns the first element of a list for which a function
+// is true or nil when there is none.
+// (find-if 'evenp '(1 2 3)) => 2
+func builtinFindIf(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("find-if needs a function and a list")
+	}
+
+	list, err := properList("find-if", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range list {
+		result, err := apply(s, args[0], []syntax.Sexpr{item})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if isTrue(result) {
+			return item, nil
+		}
+	}
+
+	return &syntax.NilExpr{}, nil
+}
+
+// builtinPosition returns the index of the first element of a list eql to
+// an item or nil when there is none.
+// (position 'b '(a b c)) => 1
+func builtinPosition(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("position needs two arguments")
+	}
+
+	list, err := properList("position", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range list {
+		if syntax.Eql(args[0], item) {
+			return newNumber(int64(i)), nil
+		}
+	}
+
+	return &syntax.NilExpr{}, nil
+}
+
+// builtinReduce combines the elements of a list from left to right with a
+// function of two arguments. The optional initial value is used as the
+// first value. An empty list without an initial value calls the function
+// with no arguments.
+// (reduce '+ '(1 2 3) 10) => 16
+func builtinReduce(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("reduce needs a function, a list and an optional initial value")
+	}
+
+	list, err := properList("reduce", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 3 {
+		list = append([]syntax.Sexpr{args[2]}, list...)
+	}
+
+	if len(list) == 0 {
+		return apply(s, args[0], nil)
+	}
+
+	result := list[0]
+
+	for _, item := range list[1:] {
+		result, err = apply(s, args[0], []syntax.Sexpr{result, item})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// controlFuncall calls a function with the rest of the arguments in
+// tail position.
+// (funcall '+ 1 2) => 3
+func controlFuncall(s *scope.Scope, args []syntax.Sexpr, k *continuation) (state, error) {
+	if len(args) < 1 {
+		return state{}, fmt.Errorf("funcall needs a function")
+	}
+
+	fn, err := functionValue(s, args[0])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	return applyFunction(s, fn, args[1:], k)
+}
+
+// controlApply calls a function with the arguments before the last one
+// followed by the elements of the last one which has to be a list.
+// (apply '+ 1 '(2 3)) => 6
+func controlApply(s *scope.Scope, args []syntax.Sexpr, k *continuation) (state, error) {
+	if len(args) < 2 {
+		return state{}, fmt.Errorf("apply needs a function and a list")
+	}
+
+	list, err := properList("apply", args[len(args)-1])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	fn, err := functionValue(s, args[0])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	spread := append(append([]syntax.Sexpr{}, args[1:len(args)-1]...), list...)
+	return applyFunction(s, fn, spread, k)
+}
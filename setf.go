@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// placeSetter stores a value in the place read by an accessor. It is
+// called with the evaluated arguments of the accessor and the value.
+type placeSetter func(s *scope.Scope, args []syntax.Sexpr, value syntax.Sexpr) (syntax.Sexpr, error)
+
+// places holds the accessors which setf knows how to update.
+var places = map[string]placeSetter{
+	"car":     appendValue(setter("setf car", true)),
+	"first":   appendValue(setter("setf first", true)),
+	"cdr":     appendValue(setter("setf cdr", false)),
+	"rest":    appendValue(setter("setf rest", false)),
+	"aref":    appendValue(builtinAset),
+	"get":     appendValue(builtinPut),
+	"gethash": setGethash,
+}
+
+// appendValue creates a place setter for a function taking the value
+// after the arguments of the accessor.
+func appendValue(fn scope.Function) placeSetter {
+	return func(s *scope.Scope, args []syntax.Sexpr, value syntax.Sexpr) (syntax.Sexpr, error) {
+		return fn(s, append(args, value))
+	}
+}
+
+// setGethash stores a value in a hash table, the optional default of
+// gethash is ignored.
+func setGethash(s *scope.Scope, args []syntax.Sexpr, value syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("setf gethash needs a key and a hash table")
+	}
+
+	return builtinPuthash(s, []syntax.Sexpr{args[0], value, args[1]})
+}
+
+// formSetf stores values in places. A place is a symbol, which is set
+// like setq, or a call to an accessor like car, aref, gethash or get.
+// It returns the last value stored.
+// (setf (get 'apple 'color) 'red (car x) 1)
+func formSetf(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) == 0 || len(ss)%2 != 0 {
+		return state{}, fmt.Errorf("setf needs pairs of places and values")
+	}
+
+	if len(ss) == 2 {
+		return setPlace(s, ss[0], ss[1], k)
+	}
+
+	return setPlace(s, ss[0], ss[1], k.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+		return formSetf(s, ss[2:], k)
+	}))
+}
+
+// setPlace evaluates the arguments of a place, then the value, and
+// stores the value in the place.
+func setPlace(s *scope.Scope, place, e syntax.Sexpr, k *continuation) (state, error) {
+	if _, ok := place.(*syntax.SymbolExpr); ok {
+		return formSetq(s, []syntax.Sexpr{place, e}, k)
+	}
+
+	cons, ok := place.(*syntax.ConsExpr)
+
+	if !ok {
+		return state{}, fmt.Errorf("setf needs a place got: %s", place)
+	}
+
+	accessor, ok := cons.Car.(*syntax.SymbolExpr)
+	var set placeSetter
+
+	if ok {
+		set, ok = places[accessor.Name]
+	}
+
+	if !ok {
+		return state{}, fmt.Errorf("setf does not know how to set: %s", place)
+	}
+
+	args, err := listToSlice(cons.Cdr)
+
+	if err != nil {
+		return state{}, err
+	}
+
+	return evalArgs(s, args, nil, k, func(args []syntax.Sexpr, k *continuation) (state, error) {
+		return evaluate(e, s, k.then(func(v syntax.Sexpr, k *continuation) (state, error) {
+			return k.result(set(s, args, v))
+		}))
+	})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+func init() {
+	specialForms = map[string]specialForm{
+		"setq":          formSetq,
+		"setf":          formSetf,
+		"lambda":        formLambda,
+		"defun":         formDefun,
+		"defmacro":      formDefmacro,
+		"defvar":        formDefvar,
+		"defparameter":  formDefparameter,
+		"define-syntax": formDefineSyntax,
+		"syntax-rules":  formSyntaxRules,
+
+		"if":     formIf,
+		"cond":   formCond,
+		"when":   formWhen,
+		"unless": formUnless,
+		"and":    formAnd,
+		"or":     formOr,
+		"progn":  formProgn,
+		"begin":  formProgn,
+
+		"let":    formLet,
+		"let*":   formLetStar,
+		"letrec": formLetrec,
+		"flet":   formFlet,
+		"labels": formLabels,
+
+		"handler-case":   formHandlerCase,
+		"ignore-errors":  formIgnoreErrors,
+		"unwind-protect": formUnwindProtect,
+		"catch":          formCatch,
+		"restart-case":   formRestartCase,
+
+		"quote":            formQuote,
+		"quasiquote":       formQuasiquote,
+		"unquote":          formUnquote,
+		"unquote-splicing": formUnquoteSplicing,
+	}
+}
+
+// formSetq changes the value of a symbol in the nearest scope where it
+// is defined or in the global scope otherwise. It will failed
+// if not enough arguments are pass to it.
+func formSetq(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 2 {
+		return state{}, fmt.Errorf("setq needs two arguments")
+	}
+
+	symbol, ok := ss[0].(*syntax.SymbolExpr)
+
+	if !ok || symbol.IsKeyword() {
+		return state{}, fmt.Errorf("setq needs a symbol got: %s", ss[0])
+	}
+
+	return evaluate(ss[1], s, k.then(func(expr syntax.Sexpr, k *continuation) (state, error) {
+		setSymbol(s, symbol, expr)
+		return pass(expr, k)
+	}))
+}
+
+// formLambda creates an anonymous function which captures the current
+// scope.
+// (lambda (x y) (+ x y))
+func formLambda(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("lambda needs a parameter list")
+	}
+
+	return k.result(newLambda("lambda", s, ss[0], ss[1:]))
+}
+
+// formDefun defines a named function in the global scope and returns
+// its name. The function captures the current scope.
+// (defun add (x y) (+ x y))
+func formDefun(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 2 {
+		return state{}, fmt.Errorf("defun needs a name and a parameter list")
+	}
+
+	symbol, ok := ss[0].(*syntax.SymbolExpr)
+
+	if !ok {
+		return state{}, fmt.Errorf("defun name has to be a symbol got: %s", ss[0])
+	}
+
+	f, err := newLambda(symbol.Name, s, ss[1], ss[2:])
+
+	if err != nil {
+		return state{}, err
+	}
+
+	s.Global().Set(symbol, f)
+	return pass(symbol, k)
+}
+
+// formIf evaluates the second argument when the first one is true,
+// otherwise it evaluates the optional third argument.
+// (if (first x) "yes" "no")
+func formIf(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 2 || len(ss) > 3 {
+		return state{}, fmt.Errorf("if needs two or three arguments")
+	}
+
+	return evaluate(ss[0], s, k.then(func(test syntax.Sexpr, k *continuation) (state, error) {
+		if isTrue(test) {
+			return evaluate(ss[1], s, k)
+		}
+
+		if len(ss) == 3 {
+			return evaluate(ss[2], s, k)
+		}
+
+		return pass(&syntax.NilExpr{}, k)
+	}))
+}
+
+// formCond evaluates the body of the first clause whose test is true.
+// A clause without a body returns the value of its test.
+// (cond ((first x) "first") ((first y) "second"))
+func formCond(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) == 0 {
+		return pass(&syntax.NilExpr{}, k)
+	}
+
+	clause := ss[0]
+	list, err := listToSlice(clause)
+
+	if err != nil || len(list) == 0 {
+		return state{}, fmt.Errorf("cond clause has to be a list got: %s", clause)
+	}
+
+	return evaluate(list[0], s, k.then(func(test syntax.Sexpr, k *continuation) (state, error) {
+		if !isTrue(test) {
+			return formCond(s, ss[1:], k)
+		}
+
+		if len(list) == 1 {
+			return pass(test, k)
+		}
+
+		return evalSequence(list[1:], s, k)
+	}))
+}
+
+// formWhen evaluates the body when the first argument is true.
+// (when (first x) (print x) x)
+func formWhen(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return conditionalBody("when", s, ss, true, k)
+}
+
+// formUnless evaluates the body when the first argument is nil.
+// (unless (first x) (print "empty"))
+func formUnless(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return conditionalBody("unless", s, ss, false, k)
+}
+
+// conditionalBody evaluates the body in ss[1:] when the truth of ss[0]
+// matches want.
+func conditionalBody(name string, s *scope.Scope, ss []syntax.Sexpr, want bool, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("%s needs a test", name)
+	}
+
+	return evaluate(ss[0], s, k.then(func(test syntax.Sexpr, k *continuation) (state, error) {
+		if isTrue(test) != want {
+			return pass(&syntax.NilExpr{}, k)
+		}
+
+		return evalSequence(ss[1:], s, k)
+	}))
+}
+
+// formAnd evaluates its arguments until one of them is nil. It returns
+// the value of the last argument evaluated.
+// (and x (first x))
+func formAnd(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	switch len(ss) {
+	case 0:
+		return pass(syntax.True, k)
+	case 1:
+		return evaluate(ss[0], s, k)
+	}
+
+	return evaluate(ss[0], s, k.then(func(result syntax.Sexpr, k *continuation) (state, error) {
+		if !isTrue(result) {
+			return pass(&syntax.NilExpr{}, k)
+		}
+
+		return formAnd(s, ss[1:], k)
+	}))
+}
+
+// formOr evaluates its arguments until one of them is true and
+// returns it.
+// (or (first x) "default")
+func formOr(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	switch len(ss) {
+	case 0:
+		return pass(&syntax.NilExpr{}, k)
+	case 1:
+		return evaluate(ss[0], s, k)
+	}
+
+	return evaluate(ss[0], s, k.then(func(result syntax.Sexpr, k *continuation) (state, error) {
+		if isTrue(result) {
+			return pass(result, k)
+		}
+
+		return formOr(s, ss[1:], k)
+	}))
+}
+
+// formProgn evaluates its arguments in order and returns the value of the
+// last one.
+// (progn (print x) x)
+func formProgn(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	return evalSequence(ss, s, k)
+}
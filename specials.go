@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// A specialFrame is a binding of a special variable by a let form or a
+// function call. The binding lasts as long as the frame is part of the
+// continuation, so it ends however the body is left and comes back when
+// a continuation enters the body again. While it lasts it is on the
+// bindings of its symbol in the thread, which makes looking it up take
+// constant time.
+type specialFrame struct {
+	thread *thread
+	symbol *syntax.SymbolExpr
+	value  syntax.Sexpr
+}
+
+func (f *specialFrame) resume(v syntax.Sexpr, k *continuation) (state, error) {
+	f.thread.unbind(f)
+	return pass(v, k)
+}
+
+func (f *specialFrame) exit(k *continuation) (state, error) {
+	f.thread.unbind(f)
+	return pass(&syntax.NilExpr{}, k)
+}
+
+func (f *specialFrame) enter(k *continuation) (state, error) {
+	f.thread.bind(f)
+	return pass(&syntax.NilExpr{}, k)
+}
+
+// bind makes a frame the innermost binding of its symbol.
+func (t *thread) bind(f *specialFrame) {
+	t.specials[f.symbol] = append(t.specials[f.symbol], f)
+}
+
+// unbind removes the binding of a frame. Frames are left innermost first
+// so it is the last binding of its symbol.
+func (t *thread) unbind(f *specialFrame) {
+	bindings := t.specials[f.symbol]
+
+	for i := len(bindings) - 1; i >= 0; i-- {
+		if bindings[i] == f {
+			t.specials[f.symbol] = append(bindings[:i], bindings[i+1:]...)
+			return
+		}
+	}
+}
+
+// isSpecial reports whether a symbol names a special variable in the
+// thread of a scope, its bindings are then dynamically scoped. A symbol
+// renamed by a macro expansion is special when the symbol it was created
+// from is. *print-circle* is special in every thread.
+func isSpecial(s *scope.Scope, symbol *syntax.SymbolExpr) bool {
+	return threadOf(s).declared[symbol.Unmarked()]
+}
+
+// declareSpecial makes a symbol a special variable in the thread of a
+// scope.
+func declareSpecial(s *scope.Scope, symbol *syntax.SymbolExpr) {
+	threadOf(s).declared[symbol.Unmarked()] = true
+}
+
+// dynamicBinding returns the innermost binding of a special variable or
+// nil when only its global value is set.
+func dynamicBinding(s *scope.Scope, symbol *syntax.SymbolExpr) *specialFrame {
+	bindings := threadOf(s).specials[symbol.Unmarked()]
+
+	if len(bindings) == 0 {
+		return nil
+	}
+	return bindings[len(bindings)-1]
+}
+
+// symbolValue returns the value of a symbol in a scope. A special
+// variable has the value of its innermost dynamic binding.
+func symbolValue(s *scope.Scope, symbol *syntax.SymbolExpr) (syntax.Sexpr, error) {
+	if isSpecial(s, symbol) {
+		if f := dynamicBinding(s, symbol); f != nil {
+			return f.value, nil
+		}
+	}
+	return s.Get(symbol)
+}
+
+// setSymbol changes the value of a symbol like setq. A special variable
+// changes in its innermost dynamic binding.
+func setSymbol(s *scope.Scope, symbol *syntax.SymbolExpr, value syntax.Sexpr) {
+	if isSpecial(s, symbol) {
+		if f := dynamicBinding(s, symbol); f != nil {
+			f.value = value
+			return
+		}
+	}
+	s.Update(symbol, value)
+}
+
+// bindVariable binds a symbol in the scope of a let body and returns the
+// continuation of the body. A special variable is bound by a frame of
+// the continuation instead, which makes its binding visible to every
+// function called by the body once the frame is entered.
+func bindVariable(s *scope.Scope, symbol *syntax.SymbolExpr, value syntax.Sexpr, k *continuation) *continuation {
+	if isSpecial(s, symbol) {
+		return k.push(&specialFrame{thread: threadOf(s), symbol: symbol.Unmarked(), value: value})
+	}
+
+	s.Set(symbol, value)
+	return k
+}
+
+// enterBindings calls then with the continuation bound once the frames
+// bindVariable pushed on k to get it are entered.
+func enterBindings(k, bound *continuation, then func(*continuation) (state, error)) (state, error) {
+	if bound == k {
+		return then(k)
+	}
+
+	return jump(k, bound.then(func(_ syntax.Sexpr, k *continuation) (state, error) {
+		return then(k)
+	}), &syntax.NilExpr{})
+}
+
+// specialName returns the symbol declared by defvar or defparameter
+// after checking the optional documentation string.
+func specialName(name string, ss []syntax.Sexpr) (*syntax.SymbolExpr, error) {
+	symbol, ok := ss[0].(*syntax.SymbolExpr)
+
+	if !ok || symbol.IsKeyword() {
+		return nil, fmt.Errorf("%s name has to be a symbol got: %s", name, ss[0])
+	}
+
+	if len(ss) == 3 && !isString(ss[2]) {
+		return nil, fmt.Errorf("%s documentation has to be a string got: %s", name, ss[2])
+	}
+
+	return symbol.Unmarked(), nil
+}
+
+// formDefvar declares a special variable and sets its global value when
+// it has none. The value is only evaluated then. It returns the name.
+// (defvar *count* 0 "Number of calls")
+func formDefvar(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 || len(ss) > 3 {
+		return state{}, fmt.Errorf("defvar needs a name, an optional value and an optional documentation")
+	}
+
+	symbol, err := specialName("defvar", ss)
+
+	if err != nil {
+		return state{}, err
+	}
+
+	declareSpecial(s, symbol)
+	global := s.Global()
+
+	if _, err := global.Get(symbol); err == nil || len(ss) == 1 {
+		return pass(symbol, k)
+	}
+
+	return evaluate(ss[1], s, k.then(func(value syntax.Sexpr, k *continuation) (state, error) {
+		global.Set(symbol, value)
+		return pass(symbol, k)
+	}))
+}
+
+// formDefparameter declares a special variable and sets its global
+// value. It returns the name.
+// (defparameter *limit* 10)
+func formDefparameter(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 2 || len(ss) > 3 {
+		return state{}, fmt.Errorf("defparameter needs a name, a value and an optional documentation")
+	}
+
+	symbol, err := specialName("defparameter", ss)
+
+	if err != nil {
+		return state{}, err
+	}
+
+	declareSpecial(s, symbol)
+
+	return evaluate(ss[1], s, k.then(func(value syntax.Sexpr, k *continuation) (state, error) {
+		s.Global().Set(symbol, value)
+		return pass(symbol, k)
+	}))
+}
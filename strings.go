@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// newString wraps a Go string into a string atom.
+func newString(s string) *syntax.AtomExpr {
+	return &syntax.AtomExpr{Token: syntax.STRING, Value: s}
+}
+
+// stringArg returns the value of a string atom.
+func stringArg(name string, e syntax.Sexpr) (string, error) {
+	atom, ok := e.(*syntax.AtomExpr)
+
+	if !ok || atom.Token != syntax.STRING {
+		return "", fmt.Errorf("%s needs a string got: %s", name, e)
+	}
+
+	return atom.Value.(string), nil
+}
+
+// stringArgs returns the values of string atoms.
+func stringArgs(name string, args []syntax.Sexpr) ([]string, error) {
+	ss := make([]string, len(args))
+
+	for i, arg := range args {
+		var err error
+
+		if ss[i], err = stringArg(name, arg); err != nil {
+			return nil, err
+		}
+	}
+
+	return ss, nil
+}
+
+// stringFunction returns a built-in function which takes one string and
+// returns a string.
+// (string-upcase "abc") => "ABC"
+func stringFunction(name string, fn func(string) string) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s needs one argument", name)
+		}
+
+		str, err := stringArg(name, args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return newString(fn(str)), nil
+	}
+}
+
+// stringPredicate returns a built-in function which is true when test is
+// true for two strings.
+// (string-prefix-p "ab" "abc") => t
+func stringPredicate(name string, test func(a, b string) bool) scope.Function {
+	return func(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s needs two arguments", name)
+		}
+
+		ss, err := stringArgs(name, args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return boolExpr(test(ss[0], ss[1])), nil
+	}
+}
+
+// builtinConcat joins strings together.
+// (concat "a" "b") => "ab"
+func builtinConcat(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	ss, err := stringArgs("concat", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newString(strings.Join(ss, "")), nil
+}
+
+// builtinStringLength returns the number of characters of a string.
+// (string-length "héllo") => 5
+func builtinStringLength(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("string-length needs one argument")
+	}
+
+	str, err := stringArg("string-length", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newNumber(int64(utf8.RuneCountInString(str))), nil
+}
+
+// builtinSubstring returns the characters of a string from a start index
+// up to an optional end index.
+// (substring "hello" 1 3) => "el"
+func builtinSubstring(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("substring needs a string, a start and an optional end")
+	}
+
+	str, err := stringArg("substring", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(str)
+	start, err := indexArg("substring", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(runes)
+
+	if len(args) == 3 && !isNull(args[2]) {
+		if end, err = indexArg("substring", args[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, i := range []int{start, end} {
+		if i > len(runes) {
+			return nil, fmt.Errorf("substring index out of range got: %d", i)
+		}
+	}
+
+	if end < start {
+		return nil, fmt.Errorf("substring end is before start got: %d", end)
+	}
+
+	return newString(string(runes[start:end])), nil
+}
+
+// builtinStringSplit splits a string around a separator or around runs of
+// whitespace when there is no separator.
+// (string-split "a,b" ",") => ("a" "b")
+func builtinStringSplit(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("string-split needs a string and an optional separator")
+	}
+
+	ss, err := stringArgs("string-split", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+
+	if len(ss) == 1 {
+		parts = strings.Fields(ss[0])
+	} else {
+		parts = strings.Split(ss[0], ss[1])
+	}
+
+	items := make([]syntax.Sexpr, len(parts))
+
+	for i, part := range parts {
+		items[i] = newString(part)
+	}
+
+	return sliceToList(items), nil
+}
+
+// builtinStringJoin joins a list of strings with an optional separator.
+// (string-join '("a" "b") ",") => "a,b"
+func builtinStringJoin(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("string-join needs a list of strings and an optional separator")
+	}
+
+	list, err := properList("string-join", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	ss, err := stringArgs("string-join", list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sep := ""
+
+	if len(args) == 2 {
+		if sep, err = stringArg("string-join", args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return newString(strings.Join(ss, sep)), nil
+}
+
+// builtinStringTrim removes whitespace or the characters of an optional
+// string from both ends of a string.
+// (string-trim "  a  ") => "a"
+func builtinStringTrim(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("string-trim needs a string and optional characters")
+	}
+
+	ss, err := stringArgs("string-trim", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ss) == 1 {
+		return newString(strings.TrimSpace(ss[0])), nil
+	}
+
+	return newString(strings.Trim(ss[0], ss[1])), nil
+}
+
+// builtinStringIndex returns the index of the first character of a
+// substring in a string or nil when it is not found.
+// (string-index "hello" "ll") => 2
+func builtinStringIndex(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("string-index needs two arguments")
+	}
+
+	ss, err := stringArgs("string-index", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i := strings.Index(ss[0], ss[1])
+
+	if i < 0 {
+		return &syntax.NilExpr{}, nil
+	}
+
+	return newNumber(int64(utf8.RuneCountInString(ss[0][:i]))), nil
+}
+
+// builtinStringReplace replaces every occurrence of a substring in a
+// string.
+// (string-replace "a-b-c" "-" "+") => "a+b+c"
+func builtinStringReplace(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("string-replace needs three arguments")
+	}
+
+	ss, err := stringArgs("string-replace", args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newString(strings.ReplaceAll(ss[0], ss[1], ss[2])), nil
+}
+
+// builtinNumberToString returns the printed form of a number.
+// (number-to-string 1/2) => "1/2"
+func builtinNumberToString(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("number-to-string needs one argument")
+	}
+
+	if !isNumber(args[0]) {
+		return nil, fmt.Errorf("number-to-string needs a number got: %s", args[0])
+	}
+
+	return newString(args[0].String()), nil
+}
+
+// builtinStringToNumber reads an integer, a ratio or a float from a
+// string like the reader does. It returns nil when the string is not a
+// number.
+// (string-to-number "-1.5") => -1.5
+func builtinStringToNumber(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("string-to-number needs one argument")
+	}
+
+	str, err := stringArg("string-to-number", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := parseNumber(strings.TrimSpace(str)); ok {
+		return n, nil
+	}
+
+	return &syntax.NilExpr{}, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// symbolFor returns the interned symbol of a name. The names nil and t
+// return nil and t like the reader.
+func symbolFor(name string) syntax.Sexpr {
+	switch name {
+	case "nil":
+		return &syntax.NilExpr{}
+	case "t":
+		return syntax.True
+	}
+	return syntax.Intern(name)
+}
+
+// symbolName returns the name of a symbol including nil and t.
+func symbolName(name string, e syntax.Sexpr) (string, error) {
+	switch e := e.(type) {
+	case *syntax.SymbolExpr:
+		return e.Name, nil
+	case *syntax.TrueExpr, *syntax.NilExpr:
+		return e.String(), nil
+	}
+	return "", fmt.Errorf("%s needs a symbol got: %s", name, e)
+}
+
+// isKeyword reports whether a s-expression is a keyword.
+func isKeyword(e syntax.Sexpr) bool {
+	symbol, ok := e.(*syntax.SymbolExpr)
+	return ok && symbol.IsKeyword()
+}
+
+// builtinIntern returns the symbol with a name.
+// (intern "foo") => foo
+func builtinIntern(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("intern needs one argument")
+	}
+
+	name, err := stringArg("intern", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return symbolFor(name), nil
+}
+
+// builtinSymbolName returns the name of a symbol as a string.
+// (symbol-name 'foo) => "foo"
+func builtinSymbolName(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("symbol-name needs one argument")
+	}
+
+	name, err := symbolName("symbol-name", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newString(name), nil
+}
+
+// builtinMakeSymbol returns a new symbol which is not interned so it is
+// different from any other symbol with the same name.
+// (make-symbol "foo")
+func builtinMakeSymbol(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("make-symbol needs one argument")
+	}
+
+	name, err := stringArg("make-symbol", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return syntax.NewSymbol(name), nil
+}
+
+// builtinGensym returns a new uninterned symbol named with an optional
+// prefix and a counter.
+// (gensym) => G1
+func builtinGensym(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("gensym takes at most one argument")
+	}
+
+	prefix := "G"
+
+	if len(args) == 1 {
+		var err error
+
+		if prefix, err = stringArg("gensym", args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return syntax.Gensym(prefix), nil
+}
+
+// builtinSymbolValue returns the value bound to a symbol in the current
+// scope or the dynamic value of a special variable. Keywords, nil and t are their own value.
+// (symbol-value 'x)
+func builtinSymbolValue(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("symbol-value needs one argument")
+	}
+
+	switch e := args[0].(type) {
+	case *syntax.SymbolExpr:
+		if e.IsKeyword() {
+			return e, nil
+		}
+		return symbolValue(s, e)
+	case *syntax.TrueExpr, *syntax.NilExpr:
+		return e, nil
+	}
+
+	return nil, fmt.Errorf("symbol-value needs a symbol got: %s", args[0])
+}
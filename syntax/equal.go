@@ -0,0 +1,272 @@
+package syntax
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Eq reports whether two s-expressions are the same object. Numbers,
+// characters and strings are only Eq to themselves even when another
+// one has the same value.
+func Eq(a, b Sexpr) bool {
+	switch a.(type) {
+	case *NilExpr:
+		_, ok := b.(*NilExpr)
+		return ok
+	case *TrueExpr:
+		_, ok := b.(*TrueExpr)
+		return ok
+	}
+	return a == b
+}
+
+// Eql reports whether two s-expressions are the same object, numbers of
+// the same type and value or the same character.
+func Eql(a, b Sexpr) bool {
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *NilExpr:
+		_, ok := b.(*NilExpr)
+		return ok
+	case *TrueExpr:
+		_, ok := b.(*TrueExpr)
+		return ok
+	case *AtomExpr:
+		atom, ok := b.(*AtomExpr)
+		return ok && a.Token != STRING && a.Token == atom.Token && numbersEqual(a.Value, atom.Value)
+	}
+	return false
+}
+
+// Equal reports whether two s-expressions are Eql, strings with the same
+// value or lists and vectors with Equal elements.
+func Equal(a, b Sexpr) bool {
+	for {
+		if Eql(a, b) {
+			return true
+		}
+
+		switch x := a.(type) {
+		case *AtomExpr:
+			y, ok := b.(*AtomExpr)
+			return ok && x.Token == STRING && y.Token == STRING && x.Value == y.Value
+		case *VectorExpr:
+			y, ok := b.(*VectorExpr)
+			return ok && itemsEqual(x.Items, y.Items, Equal)
+		case *ConsExpr:
+			y, ok := b.(*ConsExpr)
+
+			if !ok || !Equal(x.Car, y.Car) {
+				return false
+			}
+
+			a, b = x.Cdr, y.Cdr
+		default:
+			return false
+		}
+	}
+}
+
+// Equalp reports whether two s-expressions are Equal ignoring the case
+// of strings and characters and the type of numbers so 1 and 1.0 are
+// Equalp. Hash tables with the same test and Equalp values are Equalp.
+func Equalp(a, b Sexpr) bool {
+	for {
+		if Eql(a, b) {
+			return true
+		}
+
+		switch x := a.(type) {
+		case *AtomExpr:
+			y, ok := b.(*AtomExpr)
+			return ok && atomsEqualp(x, y)
+		case *VectorExpr:
+			y, ok := b.(*VectorExpr)
+			return ok && itemsEqual(x.Items, y.Items, Equalp)
+		case *HashTableExpr:
+			y, ok := b.(*HashTableExpr)
+			return ok && hashTablesEqualp(x, y)
+		case *ConsExpr:
+			y, ok := b.(*ConsExpr)
+
+			if !ok || !Equalp(x.Car, y.Car) {
+				return false
+			}
+
+			a, b = x.Cdr, y.Cdr
+		default:
+			return false
+		}
+	}
+}
+
+// itemsEqual reports whether two slices have the same length and equal
+// items.
+func itemsEqual(x, y []Sexpr, equal func(a, b Sexpr) bool) bool {
+	if len(x) != len(y) {
+		return false
+	}
+
+	for i := range x {
+		if !equal(x[i], y[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// atomsEqualp reports whether two strings or characters are the same
+// ignoring case or whether two numbers have the same value.
+func atomsEqualp(x, y *AtomExpr) bool {
+	switch {
+	case x.Token == STRING && y.Token == STRING:
+		return strings.EqualFold(x.Value.(string), y.Value.(string))
+	case x.Token == CHAR && y.Token == CHAR:
+		return strings.EqualFold(string(x.Value.(rune)), string(y.Value.(rune)))
+	case x.Token == STRING || y.Token == STRING || x.Token == CHAR || y.Token == CHAR:
+		return false
+	}
+
+	if f, ok := x.Value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		g, ok := y.Value.(float64)
+		return ok && f == g
+	}
+
+	if f, ok := y.Value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return false
+	}
+
+	r, ok := ratValue(x.Value)
+
+	if !ok {
+		return false
+	}
+
+	q, ok := ratValue(y.Value)
+	return ok && r.Cmp(q) == 0
+}
+
+// ratValue returns the exact value of a finite number.
+func ratValue(v interface{}) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case *big.Int:
+		return new(big.Rat).SetInt(v), true
+	case *big.Rat:
+		return v, true
+	case float64:
+		return new(big.Rat).SetFloat64(v), true
+	}
+	return nil, false
+}
+
+// hashTablesEqualp reports whether two hash tables have the same test and
+// the same keys with Equalp values.
+func hashTablesEqualp(x, y *HashTableExpr) bool {
+	if x.Test != y.Test || x.Count() != y.Count() {
+		return false
+	}
+
+	for _, entry := range x.entries {
+		value, ok := y.Get(entry.key)
+
+		if !ok || !Equalp(entry.value, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// numbersEqual reports whether two number or character values of the
+// same kind are equal.
+func numbersEqual(x, y interface{}) bool {
+	switch x := x.(type) {
+	case rune:
+		y, ok := y.(rune)
+		return ok && x == y
+	case int64:
+		y, ok := y.(int64)
+		return ok && x == y
+	case *big.Int:
+		y, ok := y.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	case *big.Rat:
+		y, ok := y.(*big.Rat)
+		return ok && x.Cmp(y) == 0
+	case float64:
+		y, ok := y.(float64)
+		return ok && x == y
+	}
+	return false
+}
+
+// hashDepth is how deep lists are hashed so circular lists can be hashed
+// too.
+const hashDepth = 8
+
+// hash returns a hash of a s-expression which is the same for Eql
+// s-expressions or for Equal ones when structural is set.
+func hash(e Sexpr, structural bool) uint64 {
+	h := fnv.New64a()
+	writeHash(h, e, structural, hashDepth)
+	return h.Sum64()
+}
+
+// writeHash writes the parts of a s-expression used by hash.
+func writeHash(w io.Writer, e Sexpr, structural bool, depth int) {
+	switch e := e.(type) {
+	case *NilExpr:
+		fmt.Fprint(w, "nil")
+	case *AtomExpr:
+		switch {
+		case e.Token != STRING:
+			// 0.0 and -0.0 are eql but are written differently.
+			if f, ok := e.Value.(float64); ok && f == 0 {
+				fmt.Fprintf(w, "%d 0", e.Token)
+				return
+			}
+			fmt.Fprintf(w, "%d %s", e.Token, e)
+		case structural:
+			fmt.Fprintf(w, "string %s", e.Value)
+		default:
+			fmt.Fprintf(w, "%p", e)
+		}
+	case *ConsExpr:
+		if !structural {
+			fmt.Fprintf(w, "%p", e)
+			return
+		}
+
+		fmt.Fprint(w, "(")
+
+		if depth > 0 {
+			writeHash(w, e.Car, structural, depth-1)
+			writeHash(w, e.Cdr, structural, depth-1)
+		}
+	case *VectorExpr:
+		if !structural {
+			fmt.Fprintf(w, "%p", e)
+			return
+		}
+
+		fmt.Fprintf(w, "#%d(", len(e.Items))
+
+		if depth > 0 {
+			for _, item := range e.Items {
+				writeHash(w, item, structural, depth-1)
+			}
+		}
+	default:
+		fmt.Fprintf(w, "%p", e)
+	}
+}
@@ -0,0 +1,126 @@
+package syntax
+
+import "fmt"
+
+// A HashTableExpr maps keys to values. Keys are compared with Eql or
+// with Equal when the test is "equal" so lists and strings can be used
+// as keys. Entries are kept in insertion order.
+type HashTableExpr struct {
+	Test    string
+	buckets map[uint64][]*hashEntry
+	entries []*hashEntry
+}
+
+// hashEntry is a key and its value.
+type hashEntry struct {
+	key, value Sexpr
+}
+
+// NewHashTable returns an empty hash table using the "eq", "eql" or
+// "equal" test. eq compares keys like eql.
+func NewHashTable(test string) *HashTableExpr {
+	return &HashTableExpr{Test: test, buckets: make(map[uint64][]*hashEntry)}
+}
+
+// Expr is use to satified Sexpr interface
+func (*HashTableExpr) Expr() {}
+func (h *HashTableExpr) String() string {
+	return fmt.Sprintf("#<hash-table %s %d>", h.Test, len(h.entries))
+}
+
+// same reports whether two keys are the same for the hash table test.
+func (h *HashTableExpr) same(a, b Sexpr) bool {
+	if h.Test == "equal" {
+		return Equal(a, b)
+	}
+	return Eql(a, b)
+}
+
+// find returns the hash of a key and its entry or nil when the key is
+// not in the hash table.
+func (h *HashTableExpr) find(key Sexpr) (uint64, *hashEntry) {
+	sum := hash(key, h.Test == "equal")
+
+	for _, entry := range h.buckets[sum] {
+		if h.same(entry.key, key) {
+			return sum, entry
+		}
+	}
+
+	return sum, nil
+}
+
+// Get returns the value of a key and whether the key was found.
+func (h *HashTableExpr) Get(key Sexpr) (Sexpr, bool) {
+	if _, entry := h.find(key); entry != nil {
+		return entry.value, true
+	}
+	return nil, false
+}
+
+// Put sets the value of a key.
+func (h *HashTableExpr) Put(key, value Sexpr) {
+	sum, entry := h.find(key)
+
+	if entry != nil {
+		entry.value = value
+		return
+	}
+
+	entry = &hashEntry{key: key, value: value}
+	h.buckets[sum] = append(h.buckets[sum], entry)
+	h.entries = append(h.entries, entry)
+}
+
+// Remove deletes a key and reports whether it was in the hash table.
+func (h *HashTableExpr) Remove(key Sexpr) bool {
+	sum, entry := h.find(key)
+
+	if entry == nil {
+		return false
+	}
+
+	h.buckets[sum] = removeEntry(h.buckets[sum], entry)
+
+	if len(h.buckets[sum]) == 0 {
+		delete(h.buckets, sum)
+	}
+
+	h.entries = removeEntry(h.entries, entry)
+	return true
+}
+
+// removeEntry returns entries without entry keeping their order.
+func removeEntry(entries []*hashEntry, entry *hashEntry) []*hashEntry {
+	for i, e := range entries {
+		if e == entry {
+			return append(entries[:i:i], entries[i+1:]...)
+		}
+	}
+	return entries
+}
+
+// Clear deletes every key.
+func (h *HashTableExpr) Clear() {
+	h.buckets = make(map[uint64][]*hashEntry)
+	h.entries = nil
+}
+
+// Count returns the number of keys.
+func (h *HashTableExpr) Count() int {
+	return len(h.entries)
+}
+
+// Each calls fn with every key and value in insertion order and stops at
+// the first error. Keys added by fn are not visited.
+func (h *HashTableExpr) Each(fn func(key, value Sexpr) error) error {
+	entries := h.entries
+
+	for _, entry := range entries {
+		if err := fn(entry.key, entry.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package syntax
+
+import (
+	"fmt"
+	"sync"
+)
+
+// symbols is the intern table holding the only symbol of each name.
+var symbols = struct {
+	sync.Mutex
+	names map[string]*SymbolExpr
+	count int // number of symbols created by Gensym
+}{names: make(map[string]*SymbolExpr)}
+
+// Intern returns the symbol of a name creating it the first time the
+// name is seen. Symbols with the same name are the same object so they
+// can be compared and looked up by identity.
+func Intern(name string) *SymbolExpr {
+	symbols.Lock()
+	defer symbols.Unlock()
+
+	if symbol, ok := symbols.names[name]; ok {
+		return symbol
+	}
+
+	symbol := &SymbolExpr{Token: SYMBOL, Name: name}
+	symbols.names[name] = symbol
+	return symbol
+}
+
+// NewSymbol returns a symbol which is not in the intern table so it is
+// different from every other symbol even one with the same name.
+func NewSymbol(name string) *SymbolExpr {
+	return &SymbolExpr{Token: SYMBOL, Name: name}
+}
+
+// Gensym returns a new uninterned symbol named with a prefix followed by
+// a counter.
+// G1, G2
+func Gensym(prefix string) *SymbolExpr {
+	symbols.Lock()
+	symbols.count++
+	n := symbols.count
+	symbols.Unlock()
+
+	return NewSymbol(fmt.Sprintf("%s%d", prefix, n))
+}
+
+// Rename returns a new symbol with the name of symbol and a mark. It
+// refers to symbol when it is not bound itself.
+func Rename(symbol *SymbolExpr, mark int) *SymbolExpr {
+	return &SymbolExpr{Token: symbol.Token, Name: symbol.Name, Mark: mark, base: symbol}
+}
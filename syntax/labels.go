@@ -0,0 +1,151 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Labels numbers the conses and vectors of a s-expression which have to
+// be printed with a #n= label and referenced later with #n# so printing
+// a circular list terminates.
+type Labels struct {
+	ids  map[Sexpr]int
+	next int
+}
+
+// NewLabels finds the conses and vectors of e which are reached again
+// while printing it. When shared is set every one reached more than once
+// gets a label, otherwise only the ones which are part of a cycle.
+func NewLabels(e Sexpr, shared bool) *Labels {
+	l := &Labels{ids: make(map[Sexpr]int)}
+
+	// visited is true for the values on the current path and false for
+	// the values already walked.
+	visited := make(map[Sexpr]bool)
+
+	// enter reports whether a value has to be walked and labels it
+	// when it was already seen.
+	enter := func(e Sexpr) bool {
+		if onPath, seen := visited[e]; seen {
+			if onPath || shared {
+				l.ids[e] = 0
+			}
+			return false
+		}
+
+		visited[e] = true
+		return true
+	}
+
+	var walk func(Sexpr)
+	walk = func(e Sexpr) {
+		if v, ok := e.(*VectorExpr); ok {
+			if enter(v) {
+				for _, item := range v.Items {
+					walk(item)
+				}
+				visited[v] = false
+			}
+			return
+		}
+
+		path := make([]*ConsExpr, 0)
+
+		for c, ok := e.(*ConsExpr); ok && enter(c); c, ok = e.(*ConsExpr) {
+			path = append(path, c)
+			walk(c.Car)
+			e = c.Cdr
+		}
+
+		for _, c := range path {
+			visited[c] = false
+		}
+
+		// a vector at the end of a dotted list.
+		if _, ok := e.(*VectorExpr); ok {
+			walk(e)
+		}
+	}
+
+	walk(e)
+	return l
+}
+
+// Has reports whether a cons or a vector is printed with a label.
+func (l *Labels) Has(e Sexpr) bool {
+	_, ok := l.ids[e]
+	return ok
+}
+
+// Label returns the label number of a cons or a vector and whether it
+// was already printed. The number is 0 for values printed without a
+// label. The first call for a labelled value assigns it the next number.
+func (l *Labels) Label(c Sexpr) (int, bool) {
+	n, ok := l.ids[c]
+
+	if !ok {
+		return 0, false
+	}
+
+	if n > 0 {
+		return n, true
+	}
+
+	l.next++
+	l.ids[c] = l.next
+	return l.next, false
+}
+
+// Format returns a s-expression as a string. Conses and vectors which
+// are part of a cycle are always labelled and when shared is set every
+// one reached more than once is labelled as well.
+// #1=(cons 1 #1#)
+func Format(e Sexpr, shared bool) string {
+	var buf bytes.Buffer
+	writeExpr(&buf, e, NewLabels(e, shared))
+	return buf.String()
+}
+
+// writeExpr writes a s-expression using labels for its conses and
+// vectors.
+func writeExpr(w io.Writer, e Sexpr, l *Labels) {
+	switch e.(type) {
+	case *ConsExpr, *VectorExpr:
+	default:
+		fmt.Fprintf(w, "%s", e)
+		return
+	}
+
+	n, printed := l.Label(e)
+
+	if printed {
+		fmt.Fprintf(w, "#%d#", n)
+		return
+	}
+
+	if n > 0 {
+		fmt.Fprintf(w, "#%d=", n)
+	}
+
+	if v, ok := e.(*VectorExpr); ok {
+		fmt.Fprint(w, "#(")
+
+		for i, item := range v.Items {
+			if i > 0 {
+				fmt.Fprint(w, " ")
+			}
+			writeExpr(w, item, l)
+		}
+
+		fmt.Fprint(w, ")")
+		return
+	}
+
+	c := e.(*ConsExpr)
+	fmt.Fprint(w, "(cons ")
+	writeExpr(w, c.Car, l)
+	fmt.Fprint(w, " ")
+	writeExpr(w, c.Cdr, l)
+	fmt.Fprint(w, ")")
+}
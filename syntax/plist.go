@@ -0,0 +1,130 @@
+package syntax
+
+import "sync"
+
+// plists locks the property lists of every symbol. Symbols are interned
+// once for all scopes so they can be changed by several at the same time.
+var plists sync.Mutex
+
+// Plist returns a copy of the property list of a symbol, a list
+// alternating properties and their values.
+// (color red size 2)
+func (s *SymbolExpr) Plist() Sexpr {
+	plists.Lock()
+	defer plists.Unlock()
+
+	var (
+		head Sexpr = &NilExpr{}
+		last *ConsExpr
+	)
+
+	for e, ok := s.plist.(*ConsExpr); ok; e, ok = e.Cdr.(*ConsExpr) {
+		c := &ConsExpr{Car: e.Car, Cdr: &NilExpr{}}
+
+		if last == nil {
+			head = c
+		} else {
+			last.Cdr = c
+		}
+
+		last = c
+	}
+
+	return head
+}
+
+// findProperty returns the cons holding a property of a symbol and the
+// cons holding the value before it. Both are nil when the property is
+// not set and prev is nil for the first property.
+func (s *SymbolExpr) findProperty(prop Sexpr) (prev, found *ConsExpr) {
+	e := s.plist
+
+	for {
+		c, ok := e.(*ConsExpr)
+
+		if !ok {
+			return nil, nil
+		}
+
+		value, ok := c.Cdr.(*ConsExpr)
+
+		if !ok {
+			return nil, nil
+		}
+
+		if Eql(c.Car, prop) {
+			return prev, c
+		}
+
+		prev = value
+		e = value.Cdr
+	}
+}
+
+// Property returns the value of a property of a symbol and whether it
+// is set.
+func (s *SymbolExpr) Property(prop Sexpr) (Sexpr, bool) {
+	plists.Lock()
+	defer plists.Unlock()
+
+	if _, found := s.findProperty(prop); found != nil {
+		return found.Cdr.(*ConsExpr).Car, true
+	}
+	return nil, false
+}
+
+// SetProperty sets the value of a property of a symbol. New properties
+// are added at the end of the property list.
+func (s *SymbolExpr) SetProperty(prop, value Sexpr) {
+	plists.Lock()
+	defer plists.Unlock()
+
+	if _, found := s.findProperty(prop); found != nil {
+		found.Cdr.(*ConsExpr).Car = value
+		return
+	}
+
+	entry := &ConsExpr{Car: prop, Cdr: &ConsExpr{Car: value, Cdr: &NilExpr{}}}
+
+	last, ok := s.plist.(*ConsExpr)
+
+	if !ok {
+		s.plist = entry
+		return
+	}
+
+	for {
+		next, ok := last.Cdr.(*ConsExpr)
+
+		if !ok {
+			break
+		}
+
+		last = next
+	}
+
+	last.Cdr = entry
+}
+
+// RemoveProperty deletes a property of a symbol and reports whether it
+// was set.
+func (s *SymbolExpr) RemoveProperty(prop Sexpr) bool {
+	plists.Lock()
+	defer plists.Unlock()
+
+	prev, found := s.findProperty(prop)
+
+	if found == nil {
+		return false
+	}
+
+	rest := found.Cdr.(*ConsExpr).Cdr
+
+	if prev == nil {
+		s.plist = rest
+	} else {
+		prev.Cdr = rest
+	}
+
+	return true
+}
@@ -3,6 +3,11 @@ package syntax
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 // Sexpr is a S-expression
@@ -21,7 +26,7 @@ type ConsExpr struct {
 // Expr is use to satified Sexpr interface
 func (*ConsExpr) Expr() {}
 func (c *ConsExpr) String() string {
-	return fmt.Sprintf("(cons %s %s)", c.Car, c.Cdr)
+	return Format(c, false)
 }
 
 // A NilExpr represent a "nil".
@@ -31,15 +36,53 @@ type NilExpr struct{}
 func (*NilExpr) Expr()          {}
 func (*NilExpr) String() string { return "nil" }
 
+// A TrueExpr represent "t", the canonical true value. Predicates return
+// True or nil.
+type TrueExpr struct{}
+
+// True is the only instance of TrueExpr.
+var True = &TrueExpr{}
+
+// Expr is use to satified Sexpr interface
+func (*TrueExpr) Expr()          {}
+func (*TrueExpr) String() string { return "t" }
+
 // A SymbolExpr represent the name of a symbol to be able
-// to access variables.
+// to access variables. Symbols are created with Intern so every
+// symbol with the same name is the same object.
 type SymbolExpr struct {
 	Token Token
 	Name  string
+
+	// Mark is set when the symbol was introduced by a hygienic macro
+	// expansion. Each expansion uses a new mark so a symbol bound by
+	// the expansion can not capture a symbol with the same name written
+	// by the user.
+	Mark int
+
+	base  *SymbolExpr // symbol renamed with Mark
+	plist Sexpr       // property list
 }
 
 // Expr is use to satified Sexpr interface
 func (*SymbolExpr) Expr() {}
+
+// Unmarked returns the symbol a symbol renamed by a macro expansion was
+// created from or the symbol itself when it was not renamed.
+func (s *SymbolExpr) Unmarked() *SymbolExpr {
+	if s.base != nil {
+		return s.base
+	}
+	return s
+}
+
+// IsKeyword reports whether a symbol starts with a colon. Keywords
+// evaluate to themselves.
+// :key
+func (s *SymbolExpr) IsKeyword() bool {
+	return len(s.Name) > 1 && s.Name[0] == ':'
+}
+
 func (s *SymbolExpr) String() string {
 	var buf bytes.Buffer
 	buf.WriteString(s.Name)
@@ -47,6 +90,9 @@ func (s *SymbolExpr) String() string {
 }
 
 // An AtomExpr represent all variables types.
+// The Value of an INT is an int64 or a *big.Int when it does not fit
+// in 64 bits, a RATIO is a *big.Rat, a FLOAT is a float64 and a CHAR
+// is a rune.
 type AtomExpr struct {
 	Token Token
 	Raw   string
@@ -63,8 +109,51 @@ func (a *AtomExpr) String() string {
 		fmt.Fprintf(&buf, "%q", a.Value)
 	case INT:
 		fmt.Fprintf(&buf, "%d", a.Value)
+	case RATIO:
+		buf.WriteString(a.Value.(*big.Rat).RatString())
 	case FLOAT:
-		fmt.Fprintf(&buf, "%g", a.Value)
+		buf.WriteString(floatString(a.Value.(float64)))
+	case CHAR:
+		buf.WriteString(charString(a.Value.(rune)))
 	}
 	return buf.String()
 }
+
+// floatString returns the literal of a float. It always has a decimal
+// point or an exponent so it is read back as a float and not an integer.
+// 1.0 1.5 1e+21
+func floatString(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+
+	if strings.ContainsAny(s, ".e") || math.IsInf(f, 0) || math.IsNaN(f) {
+		return s
+	}
+
+	return s + ".0"
+}
+
+// CharNames holds the characters written with a name such as #\space.
+var CharNames = map[string]rune{
+	"space":   ' ',
+	"newline": '\n',
+	"tab":     '\t',
+	"return":  '\r',
+	"nul":     0,
+}
+
+// charString returns the literal of a character. Characters which can not
+// be printed are written with their code.
+// #\a #\space #\x7F
+func charString(r rune) string {
+	for name, c := range CharNames {
+		if c == r {
+			return "#\\" + name
+		}
+	}
+
+	if unicode.IsPrint(r) {
+		return "#\\" + string(r)
+	}
+
+	return fmt.Sprintf("#\\x%X", r)
+}
@@ -33,6 +33,39 @@ const (
 
 	// RPAREN )
 	RPAREN
+
+	// QUOTE 'x
+	QUOTE
+
+	// QUASIQUOTE `x
+	QUASIQUOTE
+
+	// UNQUOTE ,x
+	UNQUOTE
+
+	// UNQUOTE_SPLICING ,@x
+	UNQUOTE_SPLICING
+
+	// RATIO atom 1/3
+	RATIO
+
+	// LABEL #1= labels the next s-expression
+	LABEL
+
+	// LABEL_REF #1# refers to a labelled s-expression
+	LABEL_REF
+
+	// VECTOR #( starts a vector
+	VECTOR
+
+	// LBRACKET [ starts a vector
+	LBRACKET
+
+	// RBRACKET ]
+	RBRACKET
+
+	// CHAR atom #\a
+	CHAR
 )
 
 func (t Token) String() string {
@@ -41,14 +74,25 @@ func (t Token) String() string {
 
 // tokenNames holds all token with their string names.
 var tokenNames = [...]string{
-	EOF:        "end of file",
-	INVALID:    "invalid token",
-	NEWLINE:    "newline",
-	WHITESPACE: "whitespace",
-	SYMBOL:     "symbol",
-	INT:        "int literal",
-	FLOAT:      "float literal",
-	STRING:     "string literal",
-	LPAREN:     "(",
-	RPAREN:     ")",
+	EOF:              "end of file",
+	INVALID:          "invalid token",
+	NEWLINE:          "newline",
+	WHITESPACE:       "whitespace",
+	SYMBOL:           "symbol",
+	INT:              "int literal",
+	FLOAT:            "float literal",
+	STRING:           "string literal",
+	LPAREN:           "(",
+	RPAREN:           ")",
+	QUOTE:            "'",
+	QUASIQUOTE:       "`",
+	UNQUOTE:          ",",
+	UNQUOTE_SPLICING: ",@",
+	RATIO:            "ratio literal",
+	LABEL:            "label",
+	LABEL_REF:        "label reference",
+	VECTOR:           "#(",
+	LBRACKET:         "[",
+	RBRACKET:         "]",
+	CHAR:             "character literal",
 }
@@ -0,0 +1,13 @@
+package syntax
+
+// A VectorExpr is a sequence of values backed by a slice so any value
+// can be read or replaced in constant time.
+type VectorExpr struct {
+	Items []Sexpr
+}
+
+// Expr is use to satified Sexpr interface
+func (*VectorExpr) Expr() {}
+func (v *VectorExpr) String() string {
+	return Format(v, false)
+}
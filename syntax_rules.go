@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// marks holds the mark used by the latest syntax-rules expansion. It is
+// shared by every scope so it is locked like the intern table.
+var marks = struct {
+	sync.Mutex
+	last int
+}{}
+
+// A renaming holds the symbols renamed by one expansion so every
+// occurrence of a symbol is renamed to the same marked symbol.
+type renaming struct {
+	mark    int
+	symbols map[*syntax.SymbolExpr]*syntax.SymbolExpr
+}
+
+// newRenaming returns the renaming of a new expansion with the next mark.
+func newRenaming() *renaming {
+	marks.Lock()
+	marks.last++
+	mark := marks.last
+	marks.Unlock()
+
+	return &renaming{mark: mark, symbols: make(map[*syntax.SymbolExpr]*syntax.SymbolExpr)}
+}
+
+// rename returns the marked symbol of a symbol.
+func (rn *renaming) rename(symbol *syntax.SymbolExpr) *syntax.SymbolExpr {
+	renamed, ok := rn.symbols[symbol]
+
+	if !ok {
+		renamed = syntax.Rename(symbol, rn.mark)
+		rn.symbols[symbol] = renamed
+	}
+
+	return renamed
+}
+
+// A syntaxRules holds the literals and rules of a syntax-rules macro.
+type syntaxRules struct {
+	literals map[string]bool
+	rules    []syntaxRule
+}
+
+// A syntaxRule is a pattern with the template used when it matches.
+type syntaxRule struct {
+	pattern  syntax.Sexpr
+	template syntax.Sexpr
+}
+
+// A ruleBinding holds what a pattern variable matched. A variable under
+// an ellipsis holds one binding for each repetition instead.
+type ruleBinding struct {
+	expr syntax.Sexpr
+	seq  []*ruleBinding
+}
+
+// formDefineSyntax defines a macro from the value of a syntax-rules form
+// in the global scope and returns its name.
+// (define-syntax swap (syntax-rules () ((_ a b) (let ((tmp a)) (setq a b) (setq b tmp)))))
+func formDefineSyntax(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) != 2 {
+		return state{}, fmt.Errorf("define-syntax needs a name and a transformer")
+	}
+
+	symbol, ok := ss[0].(*syntax.SymbolExpr)
+
+	if !ok {
+		return state{}, fmt.Errorf("define-syntax name has to be a symbol got: %s", ss[0])
+	}
+
+	return evaluate(ss[1], s, k.then(func(e syntax.Sexpr, k *continuation) (state, error) {
+		m, ok := e.(*scope.MacroExpr)
+
+		if !ok {
+			return state{}, fmt.Errorf("define-syntax needs a macro got: %s", e)
+		}
+
+		s.Global().Set(symbol, &scope.MacroExpr{Name: symbol.Name, Fn: m.Fn, Lambda: m.Lambda})
+		return pass(symbol, k)
+	}))
+}
+
+// formSyntaxRules creates a macro which expands the template of the first
+// pattern matching a call. Symbols introduced by a template get a new
+// mark on every expansion so they can not capture the user symbols.
+// (syntax-rules (literals...) ((_ pattern...) template)...)
+func formSyntaxRules(s *scope.Scope, ss []syntax.Sexpr, k *continuation) (state, error) {
+	if len(ss) < 1 {
+		return state{}, fmt.Errorf("syntax-rules needs a list of literals")
+	}
+
+	literals, err := listToSlice(ss[0])
+
+	if err != nil {
+		return state{}, fmt.Errorf("syntax-rules needs a list of literals got: %s", ss[0])
+	}
+
+	r := &syntaxRules{literals: make(map[string]bool)}
+
+	for _, l := range literals {
+		symbol, ok := l.(*syntax.SymbolExpr)
+
+		if !ok {
+			return state{}, fmt.Errorf("syntax-rules literal has to be a symbol got: %s", l)
+		}
+
+		r.literals[symbol.Name] = true
+	}
+
+	for _, rule := range ss[1:] {
+		list, err := listToSlice(rule)
+
+		if err != nil || len(list) != 2 {
+			return state{}, fmt.Errorf("Invalid syntax-rules rule: %s", rule)
+		}
+
+		// the first element of the pattern is the macro name
+		pattern, ok := list[0].(*syntax.ConsExpr)
+
+		if !ok {
+			return state{}, fmt.Errorf("syntax-rules pattern has to be a list got: %s", list[0])
+		}
+
+		r.rules = append(r.rules, syntaxRule{pattern: pattern.Cdr, template: list[1]})
+	}
+
+	return pass(&scope.MacroExpr{Name: "syntax-rules", Fn: r.expand}, k)
+}
+
+// expand returns the template of the first rule matching the arguments.
+func (r *syntaxRules) expand(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	form := sliceToList(args)
+
+	for _, rule := range r.rules {
+		bindings := make(map[string]*ruleBinding)
+
+		if r.match(rule.pattern, form, bindings) {
+			return r.instantiate(rule.template, bindings, newRenaming())
+		}
+	}
+
+	return nil, fmt.Errorf("No syntax-rules pattern matches: %s", form)
+}
+
+// match reports whether the s-expression e matches pattern p and collects
+// the pattern variables in bindings.
+func (r *syntaxRules) match(p, e syntax.Sexpr, bindings map[string]*ruleBinding) bool {
+	switch p := p.(type) {
+	case *syntax.SymbolExpr:
+		if p.Name == "_" {
+			return true
+		}
+
+		if r.literals[p.Name] {
+			symbol, ok := e.(*syntax.SymbolExpr)
+			return ok && symbol.Name == p.Name
+		}
+
+		bindings[p.Name] = &ruleBinding{expr: e}
+		return true
+	case *syntax.ConsExpr:
+		if next, ok := p.Cdr.(*syntax.ConsExpr); ok && isEllipsis(next.Car) {
+			return r.matchEllipsis(p.Car, next.Cdr, e, bindings)
+		}
+
+		cons, ok := e.(*syntax.ConsExpr)
+		return ok && r.match(p.Car, cons.Car, bindings) && r.match(p.Cdr, cons.Cdr, bindings)
+	case *syntax.NilExpr:
+		_, ok := e.(*syntax.NilExpr)
+		return ok
+	case *syntax.AtomExpr:
+		atom, ok := e.(*syntax.AtomExpr)
+		return ok && atom.Token == p.Token && atom.String() == p.String()
+	}
+	return false
+}
+
+// matchEllipsis matches as many elements of e with the pattern p as
+// possible while leaving enough elements for the rest of the pattern.
+func (r *syntaxRules) matchEllipsis(p, rest, e syntax.Sexpr, bindings map[string]*ruleBinding) bool {
+	items := make([]syntax.Sexpr, 0)
+
+	for cons, ok := e.(*syntax.ConsExpr); ok; cons, ok = cons.Cdr.(*syntax.ConsExpr) {
+		items = append(items, cons.Car)
+	}
+
+	minimum := 0
+	for cons, ok := rest.(*syntax.ConsExpr); ok; cons, ok = cons.Cdr.(*syntax.ConsExpr) {
+		minimum++
+	}
+
+	n := len(items) - minimum
+
+	if n < 0 {
+		return false
+	}
+
+	seqs := make(map[string][]*ruleBinding)
+
+	for _, v := range r.patternVars(p, nil) {
+		seqs[v] = make([]*ruleBinding, 0, n)
+	}
+
+	for _, item := range items[:n] {
+		m := make(map[string]*ruleBinding)
+
+		if !r.match(p, item, m) {
+			return false
+		}
+
+		for v := range seqs {
+			seqs[v] = append(seqs[v], m[v])
+		}
+	}
+
+	for v, seq := range seqs {
+		bindings[v] = &ruleBinding{seq: seq}
+	}
+
+	for i := 0; i < n; i++ {
+		e = e.(*syntax.ConsExpr).Cdr
+	}
+
+	return r.match(rest, e, bindings)
+}
+
+// patternVars returns the names of all pattern variables in p.
+func (r *syntaxRules) patternVars(p syntax.Sexpr, vars []string) []string {
+	switch p := p.(type) {
+	case *syntax.SymbolExpr:
+		if p.Name != "_" && !isEllipsis(p) && !r.literals[p.Name] {
+			vars = append(vars, p.Name)
+		}
+	case *syntax.ConsExpr:
+		vars = r.patternVars(p.Car, vars)
+		vars = r.patternVars(p.Cdr, vars)
+	}
+	return vars
+}
+
+// instantiate replaces the pattern variables of a template with what they
+// matched and renames every other symbol with the mark of the expansion.
+// A nil renaming leaves the symbols as they are.
+func (r *syntaxRules) instantiate(t syntax.Sexpr, bindings map[string]*ruleBinding, rn *renaming) (syntax.Sexpr, error) {
+	switch t := t.(type) {
+	case *syntax.SymbolExpr:
+		b, ok := bindings[t.Name]
+
+		if !ok && rn == nil {
+			return t, nil
+		}
+
+		if !ok {
+			return rn.rename(t), nil
+		}
+
+		if b.seq != nil {
+			return nil, fmt.Errorf("Pattern variable used without an ellipsis: %s", t.Name)
+		}
+
+		return b.expr, nil
+	case *syntax.ConsExpr:
+		// quoted symbols are data so they are not renamed.
+		if quoteForm(t) == "quote" {
+			rn = nil
+		}
+
+		next, ok := t.Cdr.(*syntax.ConsExpr)
+
+		if !ok || !isEllipsis(next.Car) {
+			car, err := r.instantiate(t.Car, bindings, rn)
+
+			if err != nil {
+				return nil, err
+			}
+
+			cdr, err := r.instantiate(t.Cdr, bindings, rn)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return &syntax.ConsExpr{Car: car, Cdr: cdr}, nil
+		}
+
+		items, err := r.instantiateEllipsis(t.Car, bindings, rn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tail, err := r.instantiate(next.Cdr, bindings, rn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := len(items) - 1; i >= 0; i-- {
+			tail = &syntax.ConsExpr{Car: items[i], Cdr: tail}
+		}
+
+		return tail, nil
+	}
+	return t, nil
+}
+
+// instantiateEllipsis instantiates the template t once for every
+// repetition of the pattern variables under the ellipsis.
+func (r *syntaxRules) instantiateEllipsis(t syntax.Sexpr, bindings map[string]*ruleBinding, rn *renaming) ([]syntax.Sexpr, error) {
+	vars := make([]string, 0)
+	n := -1
+
+	for _, v := range r.patternVars(t, nil) {
+		b, ok := bindings[v]
+
+		if !ok || b.seq == nil {
+			continue
+		}
+
+		if n != -1 && len(b.seq) != n {
+			return nil, fmt.Errorf("Pattern variables under an ellipsis have different lengths: %s", t)
+		}
+
+		n = len(b.seq)
+		vars = append(vars, v)
+	}
+
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("Ellipsis without pattern variables: %s", t)
+	}
+
+	items := make([]syntax.Sexpr, 0, n)
+
+	for i := 0; i < n; i++ {
+		local := make(map[string]*ruleBinding, len(bindings))
+
+		for k, v := range bindings {
+			local[k] = v
+		}
+
+		for _, v := range vars {
+			local[v] = bindings[v].seq[i]
+		}
+
+		item, err := r.instantiate(t, local, rn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// isEllipsis reports whether a s-expression is the symbol "...".
+func isEllipsis(e syntax.Sexpr) bool {
+	symbol, ok := e.(*syntax.SymbolExpr)
+	return ok && symbol.Name == "..."
+}
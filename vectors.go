@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miguel250/lisp-interpreter/scope"
+	"github.com/miguel250/lisp-interpreter/syntax"
+)
+
+// vectorArg returns a s-expression as a vector.
+func vectorArg(name string, e syntax.Sexpr) (*syntax.VectorExpr, error) {
+	v, ok := e.(*syntax.VectorExpr)
+
+	if !ok {
+		return nil, fmt.Errorf("%s needs a vector got: %s", name, e)
+	}
+
+	return v, nil
+}
+
+// vectorIndex returns a s-expression as an index of a vector. end allows
+// the index right after the last value.
+func vectorIndex(name string, v *syntax.VectorExpr, e syntax.Sexpr, end bool) (int, error) {
+	i, err := indexArg(name, e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if i > len(v.Items) || i == len(v.Items) && !end {
+		return 0, fmt.Errorf("%s index out of range got: %d", name, i)
+	}
+
+	return i, nil
+}
+
+// maxVectorLength is the longest vector make-vector creates, so a huge
+// length is an error and not a failed allocation.
+const maxVectorLength = 1 << 24
+
+// builtinMakeVector creates a vector of a given length with every value
+// set to the optional initial value or nil.
+// (make-vector 3 0) => #(0 0 0)
+func builtinMakeVector(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("make-vector needs a length and an optional initial value")
+	}
+
+	n, err := indexArg("make-vector", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n > maxVectorLength {
+		return nil, fmt.Errorf("make-vector length is too large got: %s", args[0])
+	}
+
+	var initial syntax.Sexpr = &syntax.NilExpr{}
+
+	if len(args) == 2 {
+		initial = args[1]
+	}
+
+	items := make([]syntax.Sexpr, n)
+
+	for i := range items {
+		items[i] = initial
+	}
+
+	return &syntax.VectorExpr{Items: items}, nil
+}
+
+// builtinVector creates a vector with its arguments.
+// (vector 1 2 3) => #(1 2 3)
+func builtinVector(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	items := make([]syntax.Sexpr, len(args))
+	copy(items, args)
+	return &syntax.VectorExpr{Items: items}, nil
+}
+
+// builtinAref returns the value at an index of a vector starting from 0.
+// (aref [1 2 3] 1) => 2
+func builtinAref(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("aref needs two arguments")
+	}
+
+	v, err := vectorArg("aref", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := vectorIndex("aref", v, args[1], false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Items[i], nil
+}
+
+// builtinAset replaces the value at an index of a vector and returns the
+// new value.
+// (aset v 1 'x)
+func builtinAset(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("aset needs three arguments")
+	}
+
+	v, err := vectorArg("aset", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := vectorIndex("aset", v, args[1], false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	v.Items[i] = args[2]
+	return args[2], nil
+}
+
+// builtinVectorLength returns the number of values in a vector.
+// (vector-length [1 2]) => 2
+func builtinVectorLength(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("vector-length needs one argument")
+	}
+
+	v, err := vectorArg("vector-length", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newNumber(int64(len(v.Items))), nil
+}
+
+// builtinVectorPush adds a value at the end of a vector and returns the
+// index of the new value.
+// (vector-push 4 v)
+func builtinVectorPush(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("vector-push needs two arguments")
+	}
+
+	v, err := vectorArg("vector-push", args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	v.Items = append(v.Items, args[0])
+	return newNumber(int64(len(v.Items) - 1)), nil
+}
+
+// builtinSubseq returns a copy of a vector or a list from a start index
+// up to an optional end index.
+// (subseq [1 2 3] 1) => #(2 3)
+func builtinSubseq(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("subseq needs a sequence, a start and an optional end")
+	}
+
+	v, isVector := args[0].(*syntax.VectorExpr)
+
+	if !isVector {
+		list, err := listToSlice(args[0])
+
+		if err != nil {
+			return nil, fmt.Errorf("subseq needs a vector or a list got: %s", args[0])
+		}
+
+		v = &syntax.VectorExpr{Items: list}
+	}
+
+	start, err := vectorIndex("subseq", v, args[1], true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(v.Items)
+
+	if len(args) == 3 && !isNull(args[2]) {
+		if end, err = vectorIndex("subseq", v, args[2], true); err != nil {
+			return nil, err
+		}
+	}
+
+	if end < start {
+		return nil, fmt.Errorf("subseq end is before start got: %d", end)
+	}
+
+	items := make([]syntax.Sexpr, end-start)
+	copy(items, v.Items[start:end])
+
+	if isVector {
+		return &syntax.VectorExpr{Items: items}, nil
+	}
+
+	return sliceToList(items), nil
+}
+
+// builtinVectorToList returns a list with the values of a vector.
+// (vector-to-list [1 2]) => (1 2)
+func builtinVectorToList(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("vector-to-list needs one argument")
+	}
+
+	v, err := vectorArg("vector-to-list", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sliceToList(v.Items), nil
+}
+
+// builtinListToVector returns a vector with the values of a list.
+// (list-to-vector '(1 2)) => #(1 2)
+func builtinListToVector(s *scope.Scope, args []syntax.Sexpr) (syntax.Sexpr, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("list-to-vector needs one argument")
+	}
+
+	list, err := properList("list-to-vector", args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &syntax.VectorExpr{Items: list}, nil
+}